@@ -1,12 +1,16 @@
 package cli
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/mattn/go-colorable"
@@ -35,9 +39,45 @@ func PrintHelp() {
 	whitePrintln("  repo                  - opens the github repository")
 	whitePrintln("  gates                 - lists available gates")
 	whitePrintln("  run \"<gates here>\"    - executes the quantum circuit with the provided gates")
+	whitePrintln("  unitary \"<gates>\"     - prints the circuit's 2^n x 2^n operator, up to 10 wires")
+	whitePrintln("  expect \"<O>\" \"<gates>\" - prints ⟨O⟩ for a sum of Pauli strings like \"Z0 Z1 + 0.5*X2\", takes the run flags")
+	whitePrintln("  compare \"<A>\" \"<B>\"   - fidelity, trace distance and TVD between two circuits, takes the run flags")
+	redPrintln("Run flags:")
+	whitePrintln("  --max-wires N         - highest wire index for state vector backends (default 24, memory doubles per wire)")
+	whitePrintln("  --shots N             - adds counts from N sampled measurements to the table")
+	whitePrintln("  --seed S              - seed for sampling, random when omitted")
+	whitePrintln("  --backend NAME        - simulator from the list below. defaults to stabilizer for clifford circuits")
+	whitePrintln("                          past --max-wires, density with --noise or resets and statevector otherwise")
+	whitePrintln("  --noise FILE          - JSON device noise model (not YAML) applied after every gate and at readout")
+	whitePrintln("  --trajectories N      - runs averaged by the trajectory backend (default 1000)")
+	whitePrintln("  --bond-dim N          - widest bond the mps backend keeps before truncating (default 64)")
+	whitePrintln("  --amplitudes B1,B2    - bitstrings the mps backend shows amplitudes for (default: all up to 12")
+	whitePrintln("                          wires, else the ones 64 samples turn up)")
+	whitePrintln("  --init STATE          - starting state as one of 0 1 + - r l per wire, like \"|0+1->\" or 0101")
+	whitePrintln("  --init-file FILE      - starting state as normalized amplitudes, one complex number per field")
+	whitePrintln("  --cache-mb N          - memory for the state snapshots j/k resume from (default 256, 0 turns them off)")
+	redPrintln("Expect flags:")
+	whitePrintln("  --barriers            - prints ⟨O⟩ after every gate instead of only at the end")
+	redPrintln("Compare flags:")
+	whitePrintln("  --barriers            - compares after every gate both circuits have, then at the end")
+	redPrintln("Unitary flags:")
+	whitePrintln("  --barrier N           - only the first N gates (default: all of them)")
+	whitePrintln("  --hide-zeros          - leaves zero entries blank")
+	whitePrintln("  --sparse              - lists only the nonzero entries as ⟨row|U|col⟩")
+	redPrintln("Backends:")
+	for _, backend := range quantum.Backends() {
+		whitePrintf("  %-21s - %s\n", backend.Name(), describeCapabilities(backend.Capabilities()))
+	}
 	redPrintln("Circuit examples:")
 	whitePrintln("  run \"z2 x1 cnot0,1 cz2,3 rz0(-pi/2*(-3^2)) toff1,2,3\"      - random gates")
 	whitePrintln("  run \"h0 cnot0,1\"                                           - creates bell pair")
+	whitePrintln("  run --shots 1024 --seed 7 \"h0 cnot0,1\"                     - bell pair with sampled counts")
+	whitePrintln("  run \"h0 cnot0,1 cnot1,2 cnot2,500\"                         - wide clifford circuit, runs on the stabilizer backend")
+	whitePrintln("  run --backend trajectory --seed 7 \"h0 depol0(0.1)\"         - noisy circuit without a density matrix")
+	whitePrintln("  run --init \"|+0⟩\" \"cnot0,1\"                                - bell pair from a |+⟩ control")
+	whitePrintln("  unitary --sparse \"h0 cnot0,1 h0\"                            - which basis states map where")
+	whitePrintln("  expect --barriers \"Z0 Z1 - X0 X1\" \"h0 cnot0,1\"             - ⟨O⟩ as the bell pair forms")
+	whitePrintln("  compare \"h0 cnot0,1\" \"h1 cnot1,0\"                          - same bell pair, built the other way round")
 	redPrintln("Notes:")
 	whitePrintln("  indexing starts at 0")
 	whitePrintln("  arithmetic operations must be explicit (yes: 2*pi, no: 2pi)")
@@ -47,6 +87,18 @@ func PrintHelp() {
 	whitePrintln("  rx0(pi/2)   - rotate x gate on wire 0 by pi/2 radians")
 	whitePrintln("  crz0,1(pi)  - controlled z rotation with control on wire 0 and target 1 for rotation pi radians")
 	whitePrintln("  toff0,1,2   - toffoli gate with control wires 0, 1 and target wire 2")
+	whitePrintln("  p0(pi/4)    - phase e^(iλ) on |1⟩, p0 on its own is the S gate")
+	whitePrintln("  rzz0,1(pi/2) - ising zz coupling, qc gates lists every gate")
+	whitePrintln("  u0(pi/2,0,pi) - general single wire gate U(θ,φ,λ), arguments are separated by commas")
+	whitePrintln("  cu0,1(pi,0,pi) - U(θ,φ,λ) on wire 1 controlled by wire 0")
+	whitePrintln("  t0' or inv(t0) - inverse of any gate, drawn as T†. sdg0 and tdg0 name S† and T†")
+	whitePrintln("  c(0,~1)ry2(pi/4) - any gate controlled by wires 0 and 1, ~ fires on |0⟩ instead of |1⟩")
+	whitePrintln("  ctrl@negctrl@x0,1,2 - the same with prefixes, the gate's first wires become its controls")
+	whitePrintln("  m0->1       - measures wire 0 into classical bit 1")
+	whitePrintln("  reset0      - returns wire 0 to |0⟩ mid-circuit, tracing it out (runs on density when it fits)")
+	whitePrintln("  depol0(0.01) - depolarizing noise on wire 0 with probability 0.01 (density or trajectory backend)")
+	whitePrintln("  x1?c0==1    - pauli x on wire 1 only if classical bit 0 is 1")
+	whitePrintln("  z2?c=0b10   - pauli z on wire 2 only if the classical register reads 0b10 (c1=1, c0=0), up to 63 bits")
 	redPrintln("Arithmetic operations for rotational gates:")
 	whitePrintln("  pi          - defined constant")
 	whitePrintln("  ()          - order of operators")
@@ -59,34 +111,57 @@ func PrintHelp() {
 
 // print available gates
 func PrintGates() {
-	gates := []quantum.GateInterface{
-		quantum.Identity(2),
-		quantum.Hadamard(),
-		quantum.PauliX(),
-		quantum.PauliY(),
-		quantum.PauliZ(),
-		quantum.CNOT(),
-		quantum.CZ(),
-		quantum.SWAP(),
-		quantum.Toffoli(),
-		quantum.T(),
-		quantum.S(),
-		quantum.Phase(),
-		quantum.Rx(1),
-		quantum.Ry(1),
-		quantum.Rz(1),
-		quantum.CRx(1),
-		quantum.CRy(1),
-		quantum.CRz(1),
-		quantum.CCX(),
-		quantum.CCZ(),
+	redPrintln("Gates & example usage:")
+	for _, spec := range quantum.GateSpecs() {
+		gate, err := spec.Sample()
+		if err != nil {
+			continue
+		}
+		// channels get their own list below
+		if _, ok := gate.(quantum.ChannelInterface); ok {
+			continue
+		}
+		line := fmt.Sprintf("%s: %s", gate.FullName(), gate.Example())
+		if len(spec.Aliases) > 0 {
+			line += fmt.Sprintf(" (also %s)", strings.Join(spec.Aliases, ", "))
+		}
+		// gates with default arguments say what leaving them out gives
+		if len(spec.Defaults) > 0 {
+			line += fmt.Sprintf(", %s without arguments is %s", spec.Name, gate.Name())
+		}
+		whitePrintln(line)
 	}
 
-	redPrintln("Gates & example usage:")
-	for _, gate := range gates {
-		// show gate.Name() and gate.Example()
-		whitePrintf("%s: %s\n", gate.FullName(), gate.Example())
+	redPrintln("Noise channels & example usage (density or trajectory backend):")
+	for _, channel := range quantum.Channels() {
+		whitePrintf("%s: %s\n", channel.FullName(), channel.Example())
+	}
+}
+
+// one line summary of what a backend can do
+func describeCapabilities(caps quantum.Capabilities) string {
+	var parts []string
+	if caps.MaxQubits > 0 {
+		parts = append(parts, fmt.Sprintf("up to %d wires", caps.MaxQubits))
+	} else {
+		parts = append(parts, "any width")
 	}
+	if caps.CliffordOnly {
+		parts = append(parts, "clifford gates only")
+	}
+	if caps.Amplitudes {
+		parts = append(parts, "amplitudes")
+	}
+	if caps.Noise {
+		parts = append(parts, "noise")
+	}
+	if caps.MidCircuitMeasurement {
+		parts = append(parts, "mid-circuit measurement")
+	}
+	if caps.AveragesMeasurements {
+		parts = append(parts, "averages measurement branches")
+	}
+	return strings.Join(parts, ", ")
 }
 
 // version
@@ -99,27 +174,400 @@ func OpenRepo() {
 	exec.Command("open", RepoURL).Run()
 }
 
-// execute interactively
-func ExecuteCircuit(gates []string) {
-	// decode args
+// flags accepted by the run command
+type RunOptions struct {
+	MaxWires int
+	Shots    int
+	Seed     int64
+	Backend  string
+	// path to a JSON device noise model
+	Noise string
+	// runs averaged by the trajectory backend
+	Trajectories int
+	// widest bond the mps backend keeps
+	BondDimension int
+	// comma separated bitstrings the mps backend reports amplitudes for
+	Amplitudes string
+	// product state the wires start in
+	Init string
+	// path to an amplitude vector the wires start in
+	InitFile string
+	// memory for the per-barrier snapshots stepping resumes from, 0 turns them off
+	CacheMB int
+}
+
+// registers the flags of every command that runs a circuit
+func addRunFlags(fs *flag.FlagSet, opts *RunOptions) {
+	fs.IntVar(&opts.MaxWires, "max-wires", quantum.MaxWires(), "highest wire index allowed")
+	fs.IntVar(&opts.Shots, "shots", 0, "number of measurement shots to sample")
+	fs.Int64Var(&opts.Seed, "seed", time.Now().UnixNano(), "seed for sampling")
+	fs.StringVar(&opts.Backend, "backend", "", "simulator to run the circuit on, picked from the circuit when empty")
+	fs.StringVar(&opts.Noise, "noise", "", "JSON device noise model to apply, YAML isn't supported")
+	fs.IntVar(&opts.Trajectories, "trajectories", 0, "runs averaged by the trajectory backend")
+	fs.IntVar(&opts.BondDimension, "bond-dim", 0, "widest bond the mps backend keeps")
+	fs.StringVar(&opts.Amplitudes, "amplitudes", "", "bitstrings the mps backend reports amplitudes for")
+	fs.StringVar(&opts.Init, "init", "", "product state the wires start in")
+	fs.StringVar(&opts.InitFile, "init-file", "", "amplitude vector file the wires start in")
+	fs.IntVar(&opts.CacheMB, "cache-mb", 256, "memory for state snapshots while stepping, 0 turns them off")
+}
+
+// parses flags, which may come before or after the positional arguments
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	return positional, nil
+}
+
+// parses run flags, which may come before or after the circuit string
+func parseRunArgs(args []string) (RunOptions, []string, error) {
+	opts := RunOptions{}
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	addRunFlags(fs, &opts)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return RunOptions{}, nil, err
+	}
+
+	// noise needs mixed states, so it picks the density backend unless told otherwise
+	if opts.Noise != "" && opts.Backend == "" {
+		opts.Backend = quantum.BackendDensity
+	}
+	return opts, positional, nil
+}
+
+// url decodes each gate in place
+func decodeGates(gates []string) error {
 	for i, gate := range gates {
 		decoded, err := url.QueryUnescape(gate)
 		if err != nil {
-			whitePrintf("Error decoding argument: %v\n", err)
-			return
+			return err
 		}
 		gates[i] = decoded
 	}
+	return nil
+}
+
+// splits a circuit string at the spaces between gates, spaces inside an argument
+// list like u0(pi/2, 0, pi) stay with their gate
+func splitGates(circuit string) []string {
+	var gates []string
+	var current strings.Builder
+	depth := 0
+	for _, r := range circuit {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ' ' && depth <= 0:
+			gates = append(gates, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	return append(gates, current.String())
+}
+
+// builds the circuit with the run flags applied, printing what went wrong otherwise
+func buildCircuit(gates []string, opts RunOptions) (quantum.Circuit, bool) {
+	if err := decodeGates(gates); err != nil {
+		whitePrintf("Error decoding argument: %v\n", err)
+		return quantum.Circuit{}, false
+	}
 
 	circuit, err := quantum.NewCircuit(gates)
 	if err != nil {
 		whitePrintf("Error creating circuit: %v\n", err)
-		return
+		return quantum.Circuit{}, false
+	}
+	if opts.Shots < 0 {
+		whitePrintf("Error creating circuit: %v\n", quantum.ErrInvalidShots)
+		return quantum.Circuit{}, false
+	}
+	circuit.Shots = opts.Shots
+	circuit.Seed = opts.Seed
+	circuit.Backend = opts.Backend
+	if opts.Init != "" && opts.InitFile != "" {
+		whitePrintf("Error loading initial state: --init and --init-file can't be used together\n")
+		return quantum.Circuit{}, false
+	}
+	if opts.Init != "" {
+		circuit.Initial, err = quantum.ParseInitialState(opts.Init)
+	} else if opts.InitFile != "" {
+		circuit.Initial, err = quantum.LoadInitialState(opts.InitFile)
+	}
+	if err != nil {
+		whitePrintf("Error loading initial state: %v\n", err)
+		return quantum.Circuit{}, false
+	}
+	// a reset traces its wire out, which the pure state backends can only show as one
+	// sampled branch, so resets run on ρ when it fits
+	if density, err := quantum.LookupBackend(quantum.BackendDensity); err == nil && circuit.Backend == "" &&
+		circuit.HasResets() && circuit.NumQubits() <= density.Capabilities().MaxQubits {
+		circuit.Backend = quantum.BackendDensity
+	}
+	// clifford circuits too wide for the state vector run in polynomial time on the
+	// stabilizer backend, as long as they start from a product of Pauli eigenstates.
+	// narrower ones stay on the state vector, which shows amplitudes and phases
+	if stateVector, err := quantum.LookupBackend(quantum.BackendStateVector); err == nil && circuit.Backend == "" &&
+		circuit.NumQubits() > stateVector.Capabilities().MaxQubits &&
+		circuit.IsClifford() && (circuit.Initial == nil || circuit.Initial.IsProduct()) {
+		circuit.Backend = quantum.BackendStabilizer
+	}
+	circuit.Trajectories = opts.Trajectories
+	circuit.BondDimension = opts.BondDimension
+	circuit.SetSnapshotBudget(opts.CacheMB << 20)
+	if opts.Amplitudes != "" {
+		circuit.Bitstrings = strings.Split(opts.Amplitudes, ",")
+	}
+	if _, err := quantum.LookupBackend(circuit.Backend); err != nil {
+		whitePrintf("Error selecting backend: %v\n", err)
+		return quantum.Circuit{}, false
+	}
+	if opts.Noise != "" {
+		circuit.Noise, err = quantum.LoadNoiseModel(opts.Noise)
+		if err != nil {
+			whitePrintf("Error loading noise model: %v\n", err)
+			return quantum.Circuit{}, false
+		}
 	}
 
+	return circuit, true
+}
+
+// execute interactively
+func ExecuteCircuit(gates []string, opts RunOptions) {
+	circuit, ok := buildCircuit(gates, opts)
+	if !ok {
+		return
+	}
 	RunInteractiveCLI(&circuit)
 }
 
+// flags accepted by the unitary command
+type UnitaryOptions struct {
+	// gates included, 0 means all of them
+	Barrier   int
+	HideZeros bool
+	Sparse    bool
+}
+
+// parses unitary flags, which may come before or after the circuit string
+func parseUnitaryArgs(args []string) (UnitaryOptions, []string, error) {
+	opts := UnitaryOptions{}
+	fs := flag.NewFlagSet("unitary", flag.ContinueOnError)
+	fs.IntVar(&opts.Barrier, "barrier", 0, "number of gates included")
+	fs.BoolVar(&opts.HideZeros, "hide-zeros", false, "leave zero entries blank")
+	fs.BoolVar(&opts.Sparse, "sparse", false, "list only the nonzero entries")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return UnitaryOptions{}, nil, err
+	}
+	return opts, positional, nil
+}
+
+// prints the circuit's operator with rows and columns labelled by basis state
+func PrintUnitary(gates []string, opts UnitaryOptions) {
+	if err := decodeGates(gates); err != nil {
+		whitePrintf("Error decoding argument: %v\n", err)
+		return
+	}
+	circuit, err := quantum.NewCircuit(gates)
+	if err != nil {
+		whitePrintf("Error creating circuit: %v\n", err)
+		return
+	}
+	barrier := opts.Barrier
+	if barrier == 0 {
+		barrier = len(circuit.Gates)
+	}
+	unitary, err := circuit.UnitaryToBarrier(barrier)
+	if err != nil {
+		whitePrintf("Error building unitary: %v\n", err)
+		return
+	}
+
+	numQubits := circuit.NumQubits()
+	keys := make([]string, unitary.Rows)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%0*b", numQubits, i)
+	}
+	cells := make([][]string, unitary.Rows)
+	for i, row := range unitary.Data {
+		cells[i] = make([]string, unitary.Cols)
+		for j, v := range row {
+			cells[i][j] = quantum.Symbofy(v)
+		}
+	}
+
+	if opts.Sparse {
+		for j := range keys {
+			for i := range keys {
+				if cells[i][j] != "0" {
+					whitePrintf("⟨%s|U|%s⟩ = %s\n", keys[i], keys[j], cells[i][j])
+				}
+			}
+		}
+		return
+	}
+
+	width := numQubits
+	for _, row := range cells {
+		for _, cell := range row {
+			width = max(width, len(cell))
+		}
+	}
+	var sb strings.Builder
+	sb.WriteString(strings.Repeat(" ", numQubits))
+	for _, key := range keys {
+		sb.WriteString(fmt.Sprintf("  %*s", width, key))
+	}
+	redPrintln(sb.String())
+	for i, row := range cells {
+		sb.Reset()
+		for _, cell := range row {
+			if opts.HideZeros && cell == "0" {
+				cell = ""
+			}
+			sb.WriteString(fmt.Sprintf("  %*s", width, cell))
+		}
+		whitePrintf("%s%s\n", color.New(color.FgRed, color.Bold).Sprint(keys[i]), sb.String())
+	}
+}
+
+// flags accepted by the expect command, the run flags plus --barriers
+type ExpectOptions struct {
+	RunOptions
+	// print ⟨O⟩ after every gate instead of only at the end
+	Barriers bool
+}
+
+// parses expect flags, which may come before or after the observable and circuit
+func parseExpectArgs(args []string) (ExpectOptions, []string, error) {
+	opts := ExpectOptions{}
+	fs := flag.NewFlagSet("expect", flag.ContinueOnError)
+	addRunFlags(fs, &opts.RunOptions)
+	fs.BoolVar(&opts.Barriers, "barriers", false, "print the expectation at every barrier")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return ExpectOptions{}, nil, err
+	}
+	if opts.Noise != "" && opts.Backend == "" {
+		opts.Backend = quantum.BackendDensity
+	}
+	return opts, positional, nil
+}
+
+// prints ⟨O⟩ at the end of the circuit or at every barrier
+func PrintExpectation(observable string, gates []string, opts ExpectOptions) {
+	o, err := quantum.ParseObservable(observable)
+	if err != nil {
+		whitePrintf("Error parsing observable: %v\n", err)
+		return
+	}
+	circuit, ok := buildCircuit(gates, opts.RunOptions)
+	if !ok {
+		return
+	}
+
+	first := len(circuit.Gates)
+	if opts.Barriers {
+		first = 1
+	}
+	for barrier := first; barrier <= len(circuit.Gates); barrier++ {
+		value, err := circuit.ExpectationToBarrier(o, barrier)
+		if err != nil {
+			whitePrintf("Error computing expectation: %v\n", err)
+			return
+		}
+		if opts.Barriers {
+			whitePrintf("%s %-6d %s ⟨%s⟩ = %.6f\n", color.New(color.FgRed, color.Bold).Sprint("Barrier"), barrier, color.New(color.FgYellow).Sprintf("%-12s", gates[barrier-1]), o, value)
+		} else {
+			whitePrintf("⟨%s⟩ = %.6f\n", o, value)
+		}
+	}
+}
+
+// flags accepted by the compare command, the run flags plus --barriers
+type CompareOptions struct {
+	RunOptions
+	// compare the two circuits after every gate they both have, not only at the end
+	Barriers bool
+}
+
+// parses compare flags, which may come before or after the two circuits
+func parseCompareArgs(args []string) (CompareOptions, []string, error) {
+	opts := CompareOptions{}
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	addRunFlags(fs, &opts.RunOptions)
+	fs.BoolVar(&opts.Barriers, "barriers", false, "compare at every barrier")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return CompareOptions{}, nil, err
+	}
+	// amplitudes are compared directly, so clifford circuits stay off the stabilizer backend
+	if opts.Noise != "" && opts.Backend == "" {
+		opts.Backend = quantum.BackendDensity
+	} else if opts.Backend == "" {
+		opts.Backend = quantum.BackendStateVector
+	}
+	return opts, positional, nil
+}
+
+// prints fidelity, trace distance and total variation distance between two circuits
+func PrintComparison(gatesA, gatesB []string, opts CompareOptions) {
+	a, ok := buildCircuit(gatesA, opts.RunOptions)
+	if !ok {
+		return
+	}
+	b, ok := buildCircuit(gatesB, opts.RunOptions)
+	if !ok {
+		return
+	}
+
+	// matching barriers while both circuits have them, then both final states
+	type pair struct {
+		label    string
+		barrierA int
+		barrierB int
+	}
+	pairs := []pair{{"end", len(a.Gates), len(b.Gates)}}
+	if opts.Barriers {
+		pairs = nil
+		shared := min(len(a.Gates), len(b.Gates))
+		for i := 1; i <= shared; i++ {
+			pairs = append(pairs, pair{strconv.Itoa(i), i, i})
+		}
+		if len(a.Gates) != len(b.Gates) {
+			pairs = append(pairs, pair{"end", len(a.Gates), len(b.Gates)})
+		}
+	}
+
+	for i, p := range pairs {
+		// circuits with measurements or resets are compared averaged over their outcomes
+		comparison, err := quantum.CompareCircuits(&a, &b, p.barrierA, p.barrierB)
+		if err != nil {
+			whitePrintf("Error comparing circuits: %v\n", err)
+			return
+		}
+		if i == 0 {
+			redPrintln(fmt.Sprintf("%-8s %12s %16s %12s", "Barrier", "Fidelity", "Trace distance", "TVD"))
+		}
+		whitePrintf("%-8s %12.6f %16.6f %12.6f\n", p.label, comparison.Fidelity, comparison.TraceDistance, comparison.TotalVariation)
+	}
+}
+
 func getSingleKey() (rune, error) {
 	var buf [1]byte
 	if _, err := syscall.Read(syscall.Stdin, buf[:]); err != nil {
@@ -177,6 +625,25 @@ func RunInteractiveCLI(circuit *quantum.Circuit) {
 				clearScreen()
 				circuit.Draw(atBarrier)
 			}
+		// pick or re-roll the outcome of a measurement sitting at this barrier
+		case '0', '1':
+			if circuit.SetOutcome(atBarrier-1, int(key-'0')) == nil {
+				clearScreen()
+				circuit.Draw(atBarrier)
+			}
+		case 'r':
+			if circuit.Reroll(atBarrier-1) == nil {
+				clearScreen()
+				circuit.Draw(atBarrier)
+			}
+		case 'e':
+			circuit.ShowEntanglement = !circuit.ShowEntanglement
+			clearScreen()
+			circuit.Draw(atBarrier)
+		case 'b':
+			circuit.ShowBloch = !circuit.ShowBloch
+			clearScreen()
+			circuit.Draw(atBarrier)
 		}
 	}
 }
@@ -204,12 +671,62 @@ func Run() {
 	case "version", "-v", "--version":
 		PrintVersion()
 	case "run":
-		if len(os.Args) < 3 {
+		opts, positional, err := parseRunArgs(os.Args[2:])
+		if err != nil {
+			whitePrintf("Error parsing flags: %v\n", err)
+			return
+		}
+		if len(positional) < 1 {
+			PrintHelp()
+			return
+		}
+		if err := quantum.SetMaxWires(opts.MaxWires); err != nil {
+			whitePrintf("Error setting max wires: %v\n", err)
+			return
+		}
+		gates := splitGates(positional[0])
+		ExecuteCircuit(gates, opts)
+	case "expect":
+		opts, positional, err := parseExpectArgs(os.Args[2:])
+		if err != nil {
+			whitePrintf("Error parsing flags: %v\n", err)
+			return
+		}
+		if len(positional) < 2 {
+			PrintHelp()
+			return
+		}
+		if err := quantum.SetMaxWires(opts.MaxWires); err != nil {
+			whitePrintf("Error setting max wires: %v\n", err)
+			return
+		}
+		PrintExpectation(positional[0], splitGates(positional[1]), opts)
+	case "compare":
+		opts, positional, err := parseCompareArgs(os.Args[2:])
+		if err != nil {
+			whitePrintf("Error parsing flags: %v\n", err)
+			return
+		}
+		if len(positional) < 2 {
+			PrintHelp()
+			return
+		}
+		if err := quantum.SetMaxWires(opts.MaxWires); err != nil {
+			whitePrintf("Error setting max wires: %v\n", err)
+			return
+		}
+		PrintComparison(splitGates(positional[0]), splitGates(positional[1]), opts)
+	case "unitary":
+		opts, positional, err := parseUnitaryArgs(os.Args[2:])
+		if err != nil {
+			whitePrintf("Error parsing flags: %v\n", err)
+			return
+		}
+		if len(positional) < 1 {
 			PrintHelp()
 			return
 		}
-		gates := strings.Split(os.Args[2], " ")
-		ExecuteCircuit(gates)
+		PrintUnitary(splitGates(positional[0]), opts)
 	default:
 		PrintHelp()
 	}
@@ -0,0 +1,174 @@
+package quantum
+
+import (
+	"fmt"
+	"sort"
+)
+
+// simulator a circuit can run on, picked by Circuit.Backend
+type Backend interface {
+	Name() string
+	Capabilities() Capabilities
+	// runs the circuit up to barrier n, which ExecuteToBarrier has already checked.
+	// measurements should go through Circuit.MeasurementOutcome and
+	// Circuit.RecordedOutcome so forced outcomes, seeds and readout error carry over
+	Run(c *Circuit, atBarrier int) (Result, error)
+}
+
+// what a backend can do, for listings and for picking one
+type Capabilities struct {
+	// widest circuit it runs, 0 when only the parser's cap applies
+	MaxQubits int
+	// fills Result.StateVector, or only Result.Amplitudes for wide state vector runs
+	Amplitudes bool
+	// runs noise channels and noise models
+	Noise bool
+	// runs measurements, resets and classically conditioned gates
+	MidCircuitMeasurement bool
+	// only runs clifford gates (see Circuit.IsClifford)
+	CliffordOnly bool
+	// results already average over every mid-circuit measurement branch, so one
+	// run is enough to sample from
+	AveragesMeasurements bool
+}
+
+// backends by name, the built-ins plus anything added with RegisterBackend
+var backends = map[string]Backend{
+	BackendStateVector: stateVectorBackend{},
+	BackendDensity:     densityBackend{},
+	BackendTrajectory:  trajectoryBackend{},
+	BackendStabilizer:  stabilizerBackend{},
+	BackendMPS:         mpsBackend{},
+}
+
+// makes a backend selectable with Circuit.Backend (and qc run --backend) under its name
+func RegisterBackend(backend Backend) error {
+	name := backend.Name()
+	if !backendNameRegex.MatchString(name) {
+		return fmt.Errorf("%w: backend name %q", ErrInvalidArgument, name)
+	}
+	if _, ok := backends[name]; ok {
+		return fmt.Errorf("%w: %s", ErrBackendExists, name)
+	}
+	backends[name] = backend
+	return nil
+}
+
+// forgets the backend registered under name, so tests can register theirs again
+func unregisterBackend(name string) {
+	delete(backends, name)
+}
+
+// backend registered under name, "" is the state vector
+func LookupBackend(name string) (Backend, error) {
+	if name == "" {
+		name = BackendStateVector
+	}
+	backend, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, name)
+	}
+	return backend, nil
+}
+
+// every registered backend sorted by name
+func Backends() []Backend {
+	list := make([]Backend, 0, len(backends))
+	for _, backend := range backends {
+		list = append(list, backend)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name() < list[j].Name()
+	})
+	return list
+}
+
+type stateVectorBackend struct{}
+
+func (stateVectorBackend) Name() string {
+	return BackendStateVector
+}
+
+func (stateVectorBackend) Capabilities() Capabilities {
+	return Capabilities{
+		MaxQubits:             maxWires + 1,
+		Amplitudes:            true,
+		MidCircuitMeasurement: true,
+	}
+}
+
+func (stateVectorBackend) Run(c *Circuit, atBarrier int) (Result, error) {
+	return c.executeStateVector(atBarrier)
+}
+
+type densityBackend struct{}
+
+func (densityBackend) Name() string {
+	return BackendDensity
+}
+
+func (densityBackend) Capabilities() Capabilities {
+	return Capabilities{
+		MaxQubits:             (maxWires + 1) / 2,
+		Noise:                 true,
+		MidCircuitMeasurement: true,
+	}
+}
+
+func (densityBackend) Run(c *Circuit, atBarrier int) (Result, error) {
+	return c.executeDensity(atBarrier)
+}
+
+type trajectoryBackend struct{}
+
+func (trajectoryBackend) Name() string {
+	return BackendTrajectory
+}
+
+func (trajectoryBackend) Capabilities() Capabilities {
+	return Capabilities{
+		MaxQubits:             maxWires + 1,
+		Noise:                 true,
+		MidCircuitMeasurement: true,
+		AveragesMeasurements:  true,
+	}
+}
+
+func (trajectoryBackend) Run(c *Circuit, atBarrier int) (Result, error) {
+	return c.executeTrajectories(atBarrier)
+}
+
+type stabilizerBackend struct{}
+
+func (stabilizerBackend) Name() string {
+	return BackendStabilizer
+}
+
+func (stabilizerBackend) Capabilities() Capabilities {
+	return Capabilities{
+		MaxQubits:             maxStabilizerWires + 1,
+		MidCircuitMeasurement: true,
+		CliffordOnly:          true,
+	}
+}
+
+func (stabilizerBackend) Run(c *Circuit, atBarrier int) (Result, error) {
+	return c.executeStabilizer(atBarrier)
+}
+
+type mpsBackend struct{}
+
+func (mpsBackend) Name() string {
+	return BackendMPS
+}
+
+func (mpsBackend) Capabilities() Capabilities {
+	return Capabilities{
+		Amplitudes:            true,
+		MidCircuitMeasurement: true,
+	}
+}
+
+func (mpsBackend) Run(c *Circuit, atBarrier int) (Result, error) {
+	return c.executeMPS(atBarrier)
+}
@@ -0,0 +1,105 @@
+package quantum
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// reports every wire as |1⟩, enough to see the circuit reach it
+type allOnesBackend struct{}
+
+func (allOnesBackend) Name() string {
+	return "allones"
+}
+
+func (allOnesBackend) Capabilities() Capabilities {
+	return Capabilities{MidCircuitMeasurement: true}
+}
+
+func (allOnesBackend) Run(c *Circuit, atBarrier int) (Result, error) {
+	classical := make([]int, c.NumCbits())
+	for i, gate := range c.Gates[:atBarrier] {
+		if _, ok := gate.Gate.(MeasureGate); ok {
+			outcome, err := c.MeasurementOutcome(i, 1)
+			if err != nil {
+				return Result{}, err
+			}
+			classical[gate.Cbits[0]] = c.RecordedOutcome(i, gate.Wires[0], outcome)
+		}
+	}
+	return Result{
+		Probabilities:     map[string]float64{strings.Repeat("1", c.NumQubits()): 1},
+		ClassicalRegister: classical,
+	}, nil
+}
+
+func TestRegisterBackend(t *testing.T) {
+	if err := RegisterBackend(allOnesBackend{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { unregisterBackend("allones") })
+	if err := RegisterBackend(allOnesBackend{}); !errors.Is(err, ErrBackendExists) {
+		t.Errorf("expected ErrBackendExists, got %v", err)
+	}
+
+	circuit, _ := NewCircuit(strings.Split("h0 m1->0", " "))
+	circuit.Backend = "allones"
+	result, err := circuit.ExecuteToBarrier(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Probabilities["11"] != 1 || result.ClassicalRegister[0] != 1 {
+		t.Errorf("unexpected result from registered backend: %+v", result)
+	}
+	counts, err := circuit.Sample(10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts["11"] != 10 {
+		t.Errorf("expected every shot to read 11, got %v", counts)
+	}
+
+	found := false
+	for _, backend := range Backends() {
+		found = found || backend.Name() == "allones"
+	}
+	if !found {
+		t.Error("registered backend missing from Backends()")
+	}
+}
+
+func TestLookupBackend(t *testing.T) {
+	for _, name := range []string{"", BackendStateVector, BackendDensity, BackendTrajectory, BackendStabilizer, BackendMPS} {
+		backend, err := LookupBackend(name)
+		if err != nil {
+			t.Fatalf("%q: %v", name, err)
+		}
+		if name != "" && backend.Name() != name {
+			t.Errorf("%q resolved to %s", name, backend.Name())
+		}
+	}
+
+	circuit, _ := NewCircuit([]string{"h0"})
+	circuit.Backend = "nope"
+	if _, err := circuit.ExecuteToBarrier(1); !errors.Is(err, ErrUnknownBackend) {
+		t.Errorf("expected ErrUnknownBackend, got %v", err)
+	}
+	if err := RegisterBackend(namedBackend("Bad Name")); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument, got %v", err)
+	}
+}
+
+type namedBackend string
+
+func (b namedBackend) Name() string {
+	return string(b)
+}
+
+func (namedBackend) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+func (namedBackend) Run(c *Circuit, atBarrier int) (Result, error) {
+	return Result{}, nil
+}
@@ -0,0 +1,60 @@
+package quantum
+
+import (
+	"fmt"
+	"math"
+)
+
+// (⟨X⟩, ⟨Y⟩, ⟨Z⟩) of one wire's reduced state, length 1 when the wire is pure and 0
+// when it's maximally mixed
+type BlochVector struct {
+	X, Y, Z float64
+}
+
+// Bloch vector of a wire, read off its reduced state ρ = (I + xX + yY + zZ)/2
+func (r Result) BlochVector(wire int) (BlochVector, error) {
+	rho, err := r.ReducedDensityMatrix([]int{wire})
+	if err != nil {
+		return BlochVector{}, err
+	}
+	return BlochVector{
+		X: 2 * real(rho.Data[0][1]),
+		Y: -2 * imag(rho.Data[0][1]),
+		Z: real(rho.Data[0][0] - rho.Data[1][1]),
+	}, nil
+}
+
+func (b BlochVector) Length() float64 {
+	return math.Sqrt(b.X*b.X + b.Y*b.Y + b.Z*b.Z)
+}
+
+func (b BlochVector) String() string {
+	// rounding error shouldn't print as -0.00
+	clean := func(v float64) float64 {
+		if math.Abs(v) < 0.005 {
+			return 0
+		}
+		return v
+	}
+	return fmt.Sprintf("(%+.2f, %+.2f, %+.2f)", clean(b.X), clean(b.Y), clean(b.Z))
+}
+
+// arrows for the direction in the x-z plane, z up and x right like the usual sphere drawing
+var blochArrows = []string{"↑", "↗", "→", "↘", "↓", "↙", "←", "↖"}
+
+// one character picture of the vector: an arrow in the x-z plane, ⊙ or ⊗ when it
+// mostly points out of or into the page along y and · when there's hardly any left
+func (b BlochVector) Glyph() string {
+	if b.Length() < 0.1 {
+		return "·"
+	}
+	if math.Abs(b.Y) > math.Hypot(b.X, b.Z) {
+		if b.Y > 0 {
+			return "⊙"
+		}
+		return "⊗"
+	}
+	angle := math.Atan2(b.X, b.Z)
+	sector := int(math.Round(angle/(math.Pi/4))+8) % 8
+	return blochArrows[sector]
+}
@@ -0,0 +1,54 @@
+package quantum
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBlochVectors(t *testing.T) {
+	s := 1 / math.Sqrt2
+	tests := []struct {
+		gates []string
+		wire  int
+		want  BlochVector
+		glyph string
+	}{
+		{[]string{"i0"}, 0, BlochVector{0, 0, 1}, "↑"},
+		{[]string{"x0"}, 0, BlochVector{0, 0, -1}, "↓"},
+		{[]string{"h0"}, 0, BlochVector{1, 0, 0}, "→"},
+		{[]string{"x0", "h0"}, 0, BlochVector{-1, 0, 0}, "←"},
+		{[]string{"h0", "s0"}, 0, BlochVector{0, 1, 0}, "⊙"},
+		{[]string{"rx0(pi/2)"}, 0, BlochVector{0, -1, 0}, "⊗"},
+		{[]string{"ry0(pi/4)"}, 0, BlochVector{s, 0, s}, "↗"},
+		{[]string{"ry0(3*pi/4)"}, 0, BlochVector{s, 0, -s}, "↘"},
+		// half of a bell pair is maximally mixed
+		{[]string{"h0", "cnot0,1"}, 1, BlochVector{0, 0, 0}, "·"},
+	}
+	for _, tt := range tests {
+		for _, backend := range []string{BackendStateVector, BackendDensity, BackendMPS} {
+			result := runCircuit(t, tt.gates, backend)
+			got, err := result.BlochVector(tt.wire)
+			if err != nil {
+				t.Fatalf("%v %s: %v", tt.gates, backend, err)
+			}
+			if math.Abs(got.X-tt.want.X) > 1e-9 || math.Abs(got.Y-tt.want.Y) > 1e-9 || math.Abs(got.Z-tt.want.Z) > 1e-9 {
+				t.Errorf("%v %s: got %v, want %v", tt.gates, backend, got, tt.want)
+			}
+			if got.Glyph() != tt.glyph {
+				t.Errorf("%v %s: glyph %s, want %s", tt.gates, backend, got.Glyph(), tt.glyph)
+			}
+		}
+	}
+
+	// clifford states come straight from the tableau
+	got, err := runCircuit(t, []string{"x0", "h0", "s0"}, BackendStabilizer).BlochVector(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != (BlochVector{0, -1, 0}) {
+		t.Errorf("stabilizer: got %v, want (0, -1, 0)", got)
+	}
+	if got.String() != "(+0.00, -1.00, +0.00)" {
+		t.Errorf("unexpected formatting %s", got.String())
+	}
+}
@@ -0,0 +1,338 @@
+package quantum
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+// noise channels are given by Kraus operators with Σ K†K = I. they also satisfy
+// GateInterface so they can sit in a circuit, Data is the identity (the noiseless
+// ideal) and the executors use Kraus instead
+type ChannelInterface interface {
+	GateInterface
+	Kraus() []Matrix
+}
+
+type Channel struct {
+	Gate
+	kraus []Matrix
+}
+
+func (ch Channel) Kraus() []Matrix {
+	return ch.kraus
+}
+
+func newChannel(name string, wires int, kraus []Matrix) Channel {
+	return Channel{
+		Gate: Gate{
+			Matrix: Identity(1 << wires).Data(),
+			name:   name,
+		},
+		kraus: kraus,
+	}
+}
+
+// scales a matrix by a real factor
+func scaled(m Matrix, factor float64) Matrix {
+	out := NewMatrix(m.Rows, m.Cols)
+	for i := range m.Data {
+		for j := range m.Data[i] {
+			out.Data[i][j] = m.Data[i][j] * complex(factor, 0)
+		}
+	}
+	return out
+}
+
+func validProbability(p float64) bool {
+	return p >= 0 && p <= 1
+}
+
+type DepolarizingChannel struct {
+	Channel
+	p     float64
+	wires int
+}
+
+func (ch DepolarizingChannel) WiresNeeded() int {
+	return ch.wires
+}
+
+func (ch DepolarizingChannel) Example() string {
+	return "depol0(0.01)"
+}
+
+func (ch DepolarizingChannel) FullName() string {
+	return "Depolarizing"
+}
+
+func (ch DepolarizingChannel) Name() string {
+	return fmt.Sprintf("Dep(%g)", ch.p)
+}
+
+// ρ -> (1-p)ρ + p I/2^n on n wires
+func Depolarizing(p float64, wires int) (ChannelInterface, error) {
+	if !validProbability(p) || wires < 1 {
+		return nil, ErrInvalidArgument
+	}
+
+	// every n-qubit Pauli string, the identity first
+	paulis := []Matrix{Identity(1).Data()}
+	for i := 0; i < wires; i++ {
+		var next []Matrix
+		for _, prefix := range paulis {
+			for _, single := range []Matrix{Identity(2).Data(), PauliX().Data(), PauliY().Data(), PauliZ().Data()} {
+				next = append(next, tensorGateMatrix(&prefix, &single))
+			}
+		}
+		paulis = next
+	}
+
+	d := float64(len(paulis))
+	kraus := []Matrix{scaled(paulis[0], math.Sqrt(1-p*(d-1)/d))}
+	for _, pauli := range paulis[1:] {
+		kraus = append(kraus, scaled(pauli, math.Sqrt(p/d)))
+	}
+
+	return DepolarizingChannel{
+		Channel: newChannel("Dep", wires, kraus),
+		p:       p,
+		wires:   wires,
+	}, nil
+}
+
+type AmplitudeDampingChannel struct {
+	Channel
+	gamma float64
+}
+
+func (ch AmplitudeDampingChannel) WiresNeeded() int {
+	return 1
+}
+
+func (ch AmplitudeDampingChannel) Example() string {
+	return "ampdamp0(0.05)"
+}
+
+func (ch AmplitudeDampingChannel) FullName() string {
+	return "Amplitude-Damping"
+}
+
+func (ch AmplitudeDampingChannel) Name() string {
+	return fmt.Sprintf("AD(%g)", ch.gamma)
+}
+
+// decays |1⟩ to |0⟩ with probability gamma, the T1 process
+func AmplitudeDamping(gamma float64) (ChannelInterface, error) {
+	if !validProbability(gamma) {
+		return nil, ErrInvalidArgument
+	}
+	kraus := []Matrix{
+		{Rows: 2, Cols: 2, Data: [][]complex128{{1, 0}, {0, complex(math.Sqrt(1-gamma), 0)}}},
+		{Rows: 2, Cols: 2, Data: [][]complex128{{0, complex(math.Sqrt(gamma), 0)}, {0, 0}}},
+	}
+	return AmplitudeDampingChannel{
+		Channel: newChannel("AD", 1, kraus),
+		gamma:   gamma,
+	}, nil
+}
+
+type PhaseDampingChannel struct {
+	Channel
+	lambda float64
+}
+
+func (ch PhaseDampingChannel) WiresNeeded() int {
+	return 1
+}
+
+func (ch PhaseDampingChannel) Example() string {
+	return "phasedamp0(0.1)"
+}
+
+func (ch PhaseDampingChannel) FullName() string {
+	return "Phase-Damping"
+}
+
+func (ch PhaseDampingChannel) Name() string {
+	return fmt.Sprintf("PD(%g)", ch.lambda)
+}
+
+// shrinks the off-diagonal of ρ by sqrt(1-lambda) without changing populations, the pure T2 process
+func PhaseDamping(lambda float64) (ChannelInterface, error) {
+	if !validProbability(lambda) {
+		return nil, ErrInvalidArgument
+	}
+	kraus := []Matrix{
+		{Rows: 2, Cols: 2, Data: [][]complex128{{1, 0}, {0, complex(math.Sqrt(1-lambda), 0)}}},
+		{Rows: 2, Cols: 2, Data: [][]complex128{{0, 0}, {0, complex(math.Sqrt(lambda), 0)}}},
+	}
+	return PhaseDampingChannel{
+		Channel: newChannel("PD", 1, kraus),
+		lambda:  lambda,
+	}, nil
+}
+
+type BitFlipChannel struct {
+	Channel
+	p float64
+}
+
+func (ch BitFlipChannel) WiresNeeded() int {
+	return 1
+}
+
+func (ch BitFlipChannel) Example() string {
+	return "bitflip0(0.02)"
+}
+
+func (ch BitFlipChannel) FullName() string {
+	return "Bit-Flip"
+}
+
+func (ch BitFlipChannel) Name() string {
+	return fmt.Sprintf("BF(%g)", ch.p)
+}
+
+// applies X with probability p
+func BitFlip(p float64) (ChannelInterface, error) {
+	if !validProbability(p) {
+		return nil, ErrInvalidArgument
+	}
+	kraus := []Matrix{
+		scaled(Identity(2).Data(), math.Sqrt(1-p)),
+		scaled(PauliX().Data(), math.Sqrt(p)),
+	}
+	return BitFlipChannel{
+		Channel: newChannel("BF", 1, kraus),
+		p:       p,
+	}, nil
+}
+
+type KrausChannel struct {
+	Channel
+	fullName string
+	wires    int
+}
+
+func (ch KrausChannel) WiresNeeded() int {
+	return ch.wires
+}
+
+func (ch KrausChannel) Example() string {
+	return fmt.Sprintf("%s%s", ch.name, exampleWires(ch.wires))
+}
+
+func (ch KrausChannel) FullName() string {
+	return ch.fullName
+}
+
+// generic channel from Kraus operators, which must be square, act on whole wires
+// and satisfy Σ K†K = I
+func NewKrausChannel(name string, kraus []Matrix) (ChannelInterface, error) {
+	if len(kraus) == 0 {
+		return nil, ErrInvalidKraus
+	}
+	dim := kraus[0].Rows
+	wires := 0
+	for 1<<wires < dim {
+		wires++
+	}
+	if dim < 2 || 1<<wires != dim {
+		return nil, ErrInvalidKraus
+	}
+
+	sum := NewMatrix(dim, dim)
+	for _, k := range kraus {
+		if k.Rows != dim || k.Cols != dim {
+			return nil, ErrInvalidKraus
+		}
+		for i := 0; i < dim; i++ {
+			for j := 0; j < dim; j++ {
+				for l := 0; l < dim; l++ {
+					sum.Data[i][j] += cmplx.Conj(k.Data[l][i]) * k.Data[l][j]
+				}
+			}
+		}
+	}
+	for i := 0; i < dim; i++ {
+		for j := 0; j < dim; j++ {
+			want := complex(0, 0)
+			if i == j {
+				want = 1
+			}
+			if cmplx.Abs(sum.Data[i][j]-want) > 1e-9 {
+				return nil, ErrInvalidKraus
+			}
+		}
+	}
+
+	return KrausChannel{
+		Channel:  newChannel(name, wires, kraus),
+		fullName: fmt.Sprintf("Kraus (%s)", name),
+		wires:    wires,
+	}, nil
+}
+
+// channels registered by name with RegisterKrausChannel
+var customChannels = map[string]ChannelInterface{}
+
+// makes a Kraus channel usable in circuit strings as <name><wires>, e.g. "leak0". it
+// goes through RegisterGate, so a name that's taken, by a gate or an earlier channel,
+// returns ErrGateExists rather than replacing it
+func RegisterKrausChannel(name string, kraus []Matrix) error {
+	if !gateNameRegex.MatchString(name) {
+		return ErrInvalidArgument
+	}
+	channel, err := NewKrausChannel(name, kraus)
+	if err != nil {
+		return err
+	}
+	err = RegisterGate(GateSpec{
+		Name:  name,
+		Wires: channel.WiresNeeded(),
+		New: func([]float64, int) (GateInterface, error) {
+			return channel, nil
+		},
+		Style: DrawNoise,
+	})
+	if err != nil {
+		return err
+	}
+	customChannels[name] = channel
+	return nil
+}
+
+// one of each built-in channel followed by the registered ones, for listings
+func Channels() []ChannelInterface {
+	depol, _ := Depolarizing(0.01, 1)
+	ampdamp, _ := AmplitudeDamping(0.05)
+	phasedamp, _ := PhaseDamping(0.1)
+	bitflip, _ := BitFlip(0.02)
+	return append([]ChannelInterface{depol, ampdamp, phasedamp, bitflip}, CustomChannels()...)
+}
+
+// channels registered with RegisterKrausChannel
+func CustomChannels() []ChannelInterface {
+	channels := make([]ChannelInterface, 0, len(customChannels))
+	for _, channel := range customChannels {
+		channels = append(channels, channel)
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].Name() < channels[j].Name()
+	})
+	return channels
+}
+
+// "0", "0,1", ... for examples
+func exampleWires(n int) string {
+	s := ""
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			s += ","
+		}
+		s += fmt.Sprint(i)
+	}
+	return s
+}
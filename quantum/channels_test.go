@@ -0,0 +1,87 @@
+package quantum
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestChannelsAreTracePreserving(t *testing.T) {
+	depol2, _ := Depolarizing(0.3, 2)
+	for _, channel := range append(Channels(), depol2) {
+		if _, err := NewKrausChannel("check", channel.Kraus()); err != nil {
+			t.Errorf("%s: %v", channel.FullName(), err)
+		}
+	}
+}
+
+func TestChannelsOnDensityBackend(t *testing.T) {
+	tests := []struct {
+		gates  string
+		want   map[string]float64
+		purity float64
+	}{
+		{"depol0(1)", map[string]float64{"0": 0.5, "1": 0.5}, 0.5},
+		{"x0 ampdamp0(1)", map[string]float64{"0": 1}, 1},
+		{"x0 bitflip0(0.25)", map[string]float64{"0": 0.25, "1": 0.75}, 0.625},
+		// dephasing keeps the populations of |+⟩ but mixes it
+		{"h0 phasedamp0(1)", map[string]float64{"0": 0.5, "1": 0.5}, 0.5},
+	}
+	for _, tt := range tests {
+		circuit, err := NewCircuit(strings.Split(tt.gates, " "))
+		if err != nil {
+			t.Fatalf("%q: %v", tt.gates, err)
+		}
+		circuit.Backend = BackendDensity
+		result, err := circuit.ExecuteToBarrier(len(circuit.Gates))
+		if err != nil {
+			t.Fatalf("%q: %v", tt.gates, err)
+		}
+		for key, p := range tt.want {
+			if math.Abs(result.Probabilities[key]-p) > 1e-9 {
+				t.Errorf("%q: P(%s) = %v, want %v", tt.gates, key, result.Probabilities[key], p)
+			}
+		}
+		if math.Abs(result.Purity-tt.purity) > 1e-9 {
+			t.Errorf("%q: purity = %v, want %v", tt.gates, result.Purity, tt.purity)
+		}
+	}
+}
+
+func TestRegisterKrausChannel(t *testing.T) {
+	// phase flip with probability 1/2
+	kraus := []Matrix{
+		scaled(Identity(2).Data(), math.Sqrt(0.5)),
+		scaled(PauliZ().Data(), math.Sqrt(0.5)),
+	}
+	if err := RegisterKrausChannel("halfflip", kraus); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { unregisterGate("halfflip") })
+	if err := RegisterKrausChannel("halfflip", kraus); !errors.Is(err, ErrGateExists) {
+		t.Errorf("expected ErrGateExists registering a channel twice, got %v", err)
+	}
+
+	circuit, err := NewCircuit(strings.Split("h0 halfflip0", " "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	circuit.Backend = BackendDensity
+	result, err := circuit.ExecuteToBarrier(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(result.Purity-0.5) > 1e-9 {
+		t.Errorf("purity = %v, want 0.5", result.Purity)
+	}
+
+	circuit.Backend = BackendStateVector
+	if _, err := circuit.ExecuteToBarrier(2); !errors.Is(err, ErrNoiseUnsupported) {
+		t.Errorf("expected ErrNoiseUnsupported, got %v", err)
+	}
+
+	if _, err := NewKrausChannel("bad", []Matrix{PauliX().Data(), PauliZ().Data()}); !errors.Is(err, ErrInvalidKraus) {
+		t.Errorf("expected ErrInvalidKraus, got %v", err)
+	}
+}
@@ -1,7 +1,6 @@
 package quantum
 
 import (
-	"errors"
 	"fmt"
 	"math"
 	"math/cmplx"
@@ -9,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 	"text/tabwriter"
+	"unicode/utf8"
 
 	"github.com/Knetic/govaluate"
 	"github.com/fatih/color"
@@ -28,11 +28,205 @@ func NewCircuit(gates []string) (Circuit, error) {
 	if numOfGates > maxGates {
 		return Circuit{}, ErrTooManyGates
 	}
+	if err := checkRegisterConditions(&circuit); err != nil {
+		return Circuit{}, err
+	}
+	if err := checkConditionBits(&circuit); err != nil {
+		return Circuit{}, err
+	}
 
 	return circuit, nil
 }
+
+// evaluates an arithmetic argument like -pi/2*(-3^2)
+func evaluateArgument(argStr string) (float64, error) {
+	expression, err := govaluate.NewEvaluableExpression(argStr)
+	if err != nil {
+		return 0, ErrInvalidArgument
+	}
+	parameters := make(map[string]interface{})
+	parameters["pi"] = math.Pi
+	result, err := expression.Evaluate(parameters)
+	if err != nil {
+		return 0, ErrInvalidArgument
+	}
+	value, ok := result.(float64)
+	if !ok {
+		return 0, ErrInvalidArgument
+	}
+	return value, nil
+}
+
+// splits an argument list at the commas outside parentheses
+func splitArguments(argStr string) []string {
+	var args []string
+	depth, start := 0, 0
+	for i, r := range argStr {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, argStr[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(args, argStr[start:])
+}
+
+// the gate inside an inverse modifier, inv(g) or g', with any condition kept after it
+func stripInverse(name string) (string, bool) {
+	body, condition := name, ""
+	if i := strings.Index(name, "?"); i >= 0 {
+		body, condition = name[:i], name[i:]
+	}
+	if strings.HasSuffix(body, "'") {
+		return body[:len(body)-1] + condition, true
+	}
+	if !strings.HasPrefix(body, "inv(") || !strings.HasSuffix(body, ")") {
+		return "", false
+	}
+	// the parenthesis after inv has to be the one closing the string
+	depth := 0
+	for i, r := range body[3:] {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 && i != len(body)-4 {
+				return "", false
+			}
+		}
+	}
+	return body[4:len(body)-1] + condition, true
+}
+
+// wire indices as circuit strings write them, each at most once
+func parseWires(parts []string) ([]int, error) {
+	wires := make([]int, 0, len(parts))
+	seen := make(map[int]bool)
+	for _, ws := range parts {
+		wire, err := strconv.Atoi(ws)
+		if err != nil || wire < 0 {
+			return nil, ErrInvalidWireFormat
+		}
+		if wire > maxParsedWire() {
+			return nil, fmt.Errorf("%w, max: %d", ErrTooManyWires, maxParsedWire())
+		}
+		if seen[wire] {
+			return nil, ErrDuplicateWire
+		}
+		seen[wire] = true
+		wires = append(wires, wire)
+	}
+	return wires, nil
+}
+
+// a gate behind a control modifier, c(0,~1)ry2(pi/4) or ctrl@negctrl@ry0,1,2(pi/4),
+// false when name has none
+func parseControlled(name string) (CircuitGate, bool, error) {
+	var controls []int
+	state := ""
+	innerName := ""
+	if match := controlListRegex.FindStringSubmatch(name); match != nil {
+		var parts []string
+		for _, part := range strings.Split(match[1], ",") {
+			part = strings.TrimSpace(part)
+			if strings.HasPrefix(part, "~") {
+				state += "0"
+				part = part[1:]
+			} else {
+				state += "1"
+			}
+			parts = append(parts, part)
+		}
+		var err error
+		if controls, err = parseWires(parts); err != nil {
+			return CircuitGate{}, true, err
+		}
+		innerName = match[2]
+	} else {
+		// prefixes stack, each takes the next of the gate's wires as its controls
+		innerName = name
+		for {
+			match := controlPrefixRegex.FindStringSubmatch(innerName)
+			if match == nil {
+				break
+			}
+			count := 1
+			if match[2] != "" {
+				count, _ = strconv.Atoi(match[2])
+			}
+			if count < 1 {
+				return CircuitGate{}, true, fmt.Errorf("%w: %s(%d)@ needs at least 1 control", ErrInvalidArgument, match[1], count)
+			}
+			bit := "1"
+			if match[1] == "negctrl" {
+				bit = "0"
+			}
+			state += strings.Repeat(bit, count)
+			innerName = match[3]
+		}
+		if state == "" {
+			return CircuitGate{}, false, nil
+		}
+
+		loc := gateWireRegex.FindStringSubmatchIndex(innerName)
+		if loc == nil || loc[4] < 0 {
+			return CircuitGate{}, true, fmt.Errorf("%w: ctrl@ needs the control wires in front of the gate's own, e.g. ctrl@x0,1", ErrInvalidWireFormat)
+		}
+		parts := strings.Split(innerName[loc[4]:loc[5]], ",")
+		if len(parts) <= len(state) {
+			return CircuitGate{}, true, fmt.Errorf("%w: %d control wire(s) leave none for the gate", ErrInvalidWireFormat, len(state))
+		}
+		var err error
+		if controls, err = parseWires(parts[:len(state)]); err != nil {
+			return CircuitGate{}, true, err
+		}
+		innerName = innerName[:loc[4]] + strings.Join(parts[len(state):], ",") + innerName[loc[5]:]
+	}
+
+	gate, err := nameToCircuitGate(innerName)
+	if err != nil {
+		return CircuitGate{}, true, err
+	}
+	for _, wire := range gate.Wires {
+		for _, control := range controls {
+			if wire == control {
+				return CircuitGate{}, true, ErrDuplicateWire
+			}
+		}
+	}
+	if gate.Gate, err = Controlled(gate.Gate, state); err != nil {
+		return CircuitGate{}, true, err
+	}
+	gate.Wires = append(controls, gate.Wires...)
+	return gate, true, nil
+}
+
 func nameToCircuitGate(name string) (CircuitGate, error) {
 	name = strings.ToLower(name)
+	if inner, ok := stripInverse(name); ok {
+		gate, err := nameToCircuitGate(inner)
+		if err != nil {
+			return CircuitGate{}, err
+		}
+		if gate.Gate, err = Dagger(gate.Gate); err != nil {
+			return CircuitGate{}, err
+		}
+		return gate, nil
+	}
+	if gate, ok, err := parseControlled(name); ok {
+		return gate, err
+	}
+	if match := measureRegex.FindStringSubmatch(name); match != nil {
+		return parseMeasurement(match[1], match[2])
+	}
+
 	match := gateWireRegex.FindStringSubmatch(name)
 	if match == nil {
 		return CircuitGate{}, ErrUnknownGate
@@ -42,98 +236,82 @@ func nameToCircuitGate(name string) (CircuitGate, error) {
 	wireStr := match[2]
 	argStr := match[3]
 
-	var gate GateInterface
+	var condition *Condition
+	if match[5] != "" {
+		var err error
+		condition, err = parseCondition(match[4], match[5])
+		if err != nil {
+			return CircuitGate{}, err
+		}
+	}
+
 	wires := []int{}
 	if wireStr != "" {
-		wireParts := strings.Split(wireStr, ",")
-		seen := make(map[int]bool)
-		for _, ws := range wireParts {
-			wire, err := strconv.Atoi(ws)
-			if err != nil || wire < 0 {
-				return CircuitGate{}, ErrInvalidWireFormat
-			}
-			if wire > maxWires {
-				return CircuitGate{}, ErrTooManyWires
-			}
-			if seen[wire] {
-				return CircuitGate{}, ErrDuplicateWire
-			}
-			seen[wire] = true
-			wires = append(wires, wire)
+		var err error
+		if wires, err = parseWires(strings.Split(wireStr, ",")); err != nil {
+			return CircuitGate{}, err
 		}
 	}
 
-	// parse arg str if exists
-	var theta float64
+	spec, ok := gateSpecsByName[gateName]
+	if !ok {
+		return CircuitGate{}, ErrUnknownGate
+	}
+	if spec.Wires > 0 && len(wires) != spec.Wires {
+		return CircuitGate{}, fmt.Errorf("%s gate requires %d wire(s)", gateName, spec.Wires)
+	}
+	if spec.Wires == 0 && len(wires) == 0 {
+		return CircuitGate{}, fmt.Errorf("%s gate requires at least 1 wire", gateName)
+	}
+
+	// parse arg str if exists, leaving it out gives the spec's defaults
+	params := spec.arguments()
 	if argStr != "" {
-		expression, err := govaluate.NewEvaluableExpression(argStr)
-		if err != nil {
-			return CircuitGate{}, ErrInvalidArgument
+		args := splitArguments(argStr)
+		if len(args) != spec.Params {
+			return CircuitGate{}, fmt.Errorf("%w: %s gate takes %d argument(s), got %d", ErrInvalidArgument, gateName, spec.Params, len(args))
+		}
+		for i, arg := range args {
+			value, err := evaluateArgument(arg)
+			if err != nil {
+				return CircuitGate{}, fmt.Errorf("%w: argument %d of %s, %q", err, i+1, gateName, strings.TrimSpace(arg))
+			}
+			params[i] = value
 		}
-		parameters := make(map[string]interface{})
-		parameters["pi"] = math.Pi
-		result, err := expression.Evaluate(parameters)
-		if err != nil {
-			return CircuitGate{}, ErrInvalidArgument
-		}
-		argValue, ok := result.(float64)
-		if !ok {
-			return CircuitGate{}, ErrInvalidArgument
-		}
-		theta = argValue
-	}
-
-	// definitely better ways to do this... but...
-	switch gateName {
-	case "i":
-		gate = Identity(2)
-	case "h":
-		gate = Hadamard()
-	case "x":
-		gate = PauliX()
-	case "y":
-		gate = PauliY()
-	case "z":
-		gate = PauliZ()
-	case "cnot":
-		gate = CNOT()
-	case "swap":
-		gate = SWAP()
-	case "cz":
-		gate = CZ()
-	case "t":
-		gate = T()
-	case "s":
-		gate = S()
-	case "p":
-		gate = Phase()
-	case "toff":
-		gate = Toffoli()
-	case "ccx":
-		gate = CCX()
-	case "ccz":
-		gate = CCZ()
-	case "rx":
-		gate = Rx(theta)
-	case "ry":
-		gate = Ry(theta)
-	case "rz":
-		gate = Rz(theta)
-	case "crx":
-		gate = CRx(theta)
-	case "cry":
-		gate = CRy(theta)
-	case "crz":
-		gate = CRz(theta)
-	default:
-		return CircuitGate{}, ErrUnknownGate
 	}
 
+	gate, err := spec.New(params, len(wires))
+	if err != nil {
+		return CircuitGate{}, err
+	}
+	if _, ok := gate.(MeasureGate); ok {
+		return CircuitGate{}, fmt.Errorf("%w: measurements write a classical bit, e.g. m0->0", ErrInvalidWireFormat)
+	}
 	if len(wires) != gate.WiresNeeded() {
 		return CircuitGate{}, fmt.Errorf("%s gate requires %d wire(s)", gateName, gate.WiresNeeded())
 	}
 
-	return CircuitGate{Gate: gate, Wires: wires}, nil
+	return CircuitGate{Gate: gate, Wires: wires, Condition: condition}, nil
+}
+
+// builds a measurement of wireStr into the classical bit cbitStr
+func parseMeasurement(wireStr, cbitStr string) (CircuitGate, error) {
+	wire, err := strconv.Atoi(wireStr)
+	if err != nil {
+		return CircuitGate{}, ErrInvalidWireFormat
+	}
+	if wire > maxParsedWire() {
+		return CircuitGate{}, fmt.Errorf("%w, max: %d", ErrTooManyWires, maxParsedWire())
+	}
+	cbit, err := strconv.Atoi(cbitStr)
+	if err != nil {
+		return CircuitGate{}, ErrInvalidWireFormat
+	}
+	// the register is allocated up to the highest bit, so it's capped like wires
+	if cbit > maxParsedWire() {
+		return CircuitGate{}, fmt.Errorf("%w, max: %d", ErrTooManyCbits, maxParsedWire())
+	}
+	return CircuitGate{Gate: Measure(), Wires: []int{wire}, Cbits: []int{cbit}}, nil
 }
 
 func (c *Circuit) Draw(atBarrier int) error {
@@ -142,23 +320,17 @@ func (c *Circuit) Draw(atBarrier int) error {
 	wireColor := color.New(color.FgWhite).SprintfFunc()
 	barrierColor := color.New(color.BgRed).SprintfFunc()
 	gateColor := color.New(color.FgYellow).SprintfFunc()
+	noiseColor := color.New(color.FgMagenta).SprintfFunc()
 
 	longestNameSize := 0
 	for _, gate := range c.Gates {
-		if len(gate.Gate.Name()) > longestNameSize {
-			longestNameSize = len(gate.Gate.Name())
+		if utf8.RuneCountInString(gate.Gate.Name()) > longestNameSize {
+			longestNameSize = utf8.RuneCountInString(gate.Gate.Name())
 		}
 	}
 
 	var sb strings.Builder
-	numQubits := 0
-	for _, gate := range c.Gates {
-		for _, wire := range gate.Wires {
-			if wire >= numQubits {
-				numQubits = wire + 1
-			}
-		}
-	}
+	numQubits := c.NumQubits()
 
 	sb.WriteString(color.New(color.FgRed, color.Bold).SprintfFunc()("Barrier "))
 	sb.WriteString(color.New(color.FgGreen, color.Bold).SprintfFunc()(fmt.Sprintf("%d", atBarrier)))
@@ -170,12 +342,33 @@ func (c *Circuit) Draw(atBarrier int) error {
 	sb.WriteString(color.New(color.FgGreen, color.Bold).SprintfFunc()("j"))
 	sb.WriteString(color.New(color.FgRed, color.Bold).SprintfFunc()(" and "))
 	sb.WriteString(color.New(color.FgGreen, color.Bold).SprintfFunc()("k"))
-	sb.WriteString(color.New(color.FgRed, color.Bold).SprintfFunc()(" to traverse circuit\r\n"))
+	sb.WriteString(color.New(color.FgRed, color.Bold).SprintfFunc()(" to traverse circuit · "))
+	sb.WriteString(color.New(color.FgGreen, color.Bold).SprintfFunc()("e"))
+	sb.WriteString(color.New(color.FgRed, color.Bold).SprintfFunc()(" to toggle entanglement · "))
+	sb.WriteString(color.New(color.FgGreen, color.Bold).SprintfFunc()("b"))
+	sb.WriteString(color.New(color.FgRed, color.Bold).SprintfFunc()(" to toggle bloch vectors"))
+	if c.hasChoosableOutcomes() {
+		sb.WriteString(color.New(color.FgRed, color.Bold).SprintfFunc()(" · "))
+		sb.WriteString(color.New(color.FgGreen, color.Bold).SprintfFunc()("0"))
+		sb.WriteString(color.New(color.FgRed, color.Bold).SprintfFunc()(" or "))
+		sb.WriteString(color.New(color.FgGreen, color.Bold).SprintfFunc()("1"))
+		sb.WriteString(color.New(color.FgRed, color.Bold).SprintfFunc()(" to pick this measurement's outcome · "))
+		sb.WriteString(color.New(color.FgGreen, color.Bold).SprintfFunc()("r"))
+		sb.WriteString(color.New(color.FgRed, color.Bold).SprintfFunc()(" to re-roll it"))
+	}
+	sb.WriteString("\r\n")
 	sb.WriteString("\n")
 
 	qubitLines := make([]string, numQubits)
 	for i := 0; i < numQubits; i++ {
-		qubitLines[i] = qubitColor("|0⟩")
+		qubitLines[i] = qubitColor(c.Initial.Label(i))
+	}
+
+	// one double line per classical bit
+	numCbits := c.NumCbits()
+	classicalLines := make([]string, numCbits)
+	for i := 0; i < numCbits; i++ {
+		classicalLines[i] = qubitColor(fmt.Sprintf("%-3s", fmt.Sprintf("c%d", i)))
 	}
 
 	barrierPositions := []int{}
@@ -186,86 +379,102 @@ func (c *Circuit) Draw(atBarrier int) error {
 		}
 
 		gateStr := gate.Gate.Name()
-		segmentSize := len(gateStr) + 4
-		padding := segmentSize - len(gateStr)
+		// widths count runes, T† is two columns wide
+		width := utf8.RuneCountInString(gateStr)
+		segmentSize := width + 4
+		padding := segmentSize - width
 		leftPad := (padding + 1) / 2
 		rightPad := padding / 2
 
-		if len(gate.Wires) == 1 {
-			wire := gate.Wires[0]
-			qubitLines[wire] += fmt.Sprintf("%s%s%s", wireColor(strings.Repeat("-", leftPad)), gateColor(strings.ToUpper(gateStr)), wireColor(strings.Repeat("-", rightPad)))
-			for i := 0; i < numQubits; i++ {
-				if i != wire {
-					qubitLines[i] += wireColor(strings.Repeat("-", segmentSize))
+		// every wire gets a segment of the same width: the gate's name, a control dot or plain wire
+		segments := make(map[int]string)
+		wires, style := gate.Wires, drawStyleOf(gate.Gate)
+		// modifier controls are • on |1⟩ and ○ on |0⟩, the wires after them draw the way
+		// the controlled gate does on its own
+		if controlled, ok := gate.Gate.(ControlledGate); ok {
+			for j, control := range wires[:len(controlled.state)] {
+				dot := "•"
+				if controlled.state[j] == '0' {
+					dot = "○"
 				}
+				segments[control] = fmt.Sprintf("%s%s%s", wireColor(strings.Repeat("-", leftPad)), gateColor(strings.Repeat(dot, width)), wireColor(strings.Repeat("-", rightPad)))
 			}
-		} else if len(gate.Wires) == 2 {
-			control := gate.Wires[0]
-			target := gate.Wires[1]
-			minWire := control
-			maxWire := target
-			if control > target {
-				minWire = target
-				maxWire = control
+			wires, style = wires[len(controlled.state):], drawStyleOf(controlled.inner)
+		}
+		switch {
+		case style == DrawNoise:
+			// noise is drawn with wavy padding in its own color on every wire it touches
+			for _, wire := range wires {
+				segments[wire] = noiseColor(fmt.Sprintf("%s%s%s", strings.Repeat("~", leftPad), strings.ToUpper(gateStr), strings.Repeat("~", rightPad)))
 			}
-
-			if gateStr == SWAP().Name() {
-				controlStrPadded := fmt.Sprintf("%s%s%s", wireColor(strings.Repeat("-", leftPad)), gateColor(gateStr), wireColor(strings.Repeat("-", rightPad)))
-				targetStrPadded := fmt.Sprintf("%s%s%s", wireColor(strings.Repeat("-", leftPad)), gateColor(gateStr), wireColor(strings.Repeat("-", rightPad)))
-
-				for i := 0; i < numQubits; i++ {
-					if i == control {
-						qubitLines[i] += controlStrPadded
-					} else if i == target {
-						qubitLines[i] += targetStrPadded
-					} else {
-						qubitLines[i] += wireColor(strings.Repeat("-", segmentSize))
-					}
-				}
+		case style == DrawControlled && len(wires) > 1:
+			controlStr := gateColor(strings.Repeat("•", width))
+			for _, control := range wires[:len(wires)-1] {
+				segments[control] = fmt.Sprintf("%s%s%s", wireColor(strings.Repeat("-", leftPad)), controlStr, wireColor(strings.Repeat("-", rightPad)))
+			}
+			target := wires[len(wires)-1]
+			segments[target] = fmt.Sprintf("%s%s%s", wireColor(strings.Repeat("-", leftPad)), gateColor(strings.ToUpper(gateStr)), wireColor(strings.Repeat("-", rightPad)))
+		default:
+			for _, wire := range wires {
+				segments[wire] = fmt.Sprintf("%s%s%s", wireColor(strings.Repeat("-", leftPad)), gateColor(strings.ToUpper(gateStr)), wireColor(strings.Repeat("-", rightPad)))
+			}
+		}
+		for i := 0; i < numQubits; i++ {
+			if segment, ok := segments[i]; ok {
+				qubitLines[i] += segment
 			} else {
-				controlStr := gateColor(strings.Repeat("•", len(gateStr)))
-				controlStrPadded := fmt.Sprintf("%s%s%s", wireColor(strings.Repeat("-", leftPad)), controlStr, wireColor(strings.Repeat("-", rightPad)))
-
-				for i := 0; i < numQubits; i++ {
-					if i == control {
-						qubitLines[i] += controlStrPadded
-					} else if i == target {
-						qubitLines[i] += fmt.Sprintf("%s%s%s", wireColor(strings.Repeat("-", leftPad)), gateColor(strings.ToUpper(gateStr)), wireColor(strings.Repeat("-", rightPad)))
-					} else if i > minWire && i < maxWire {
-						qubitLines[i] += wireColor(strings.Repeat("-", segmentSize))
-					} else {
-						qubitLines[i] += wireColor(strings.Repeat("-", segmentSize))
-					}
-				}
+				qubitLines[i] += wireColor(strings.Repeat("-", segmentSize))
 			}
-		} else if len(gate.Wires) == 3 {
-			control1 := gate.Wires[0]
-			control2 := gate.Wires[1]
-			target := gate.Wires[2]
-			controlStr := gateColor(strings.Repeat("•", len(gateStr)))
-			controlStrPadded := fmt.Sprintf("%s%s%s", wireColor(strings.Repeat("-", leftPad)), controlStr, wireColor(strings.Repeat("-", rightPad)))
-
-			for i := 0; i < numQubits; i++ {
-				if i == control1 || i == control2 {
-					qubitLines[i] += controlStrPadded
-				} else if i == target {
-					qubitLines[i] += fmt.Sprintf("%s%s%s", wireColor(strings.Repeat("-", leftPad)), gateColor(strings.ToUpper(gateStr)), wireColor(strings.Repeat("-", rightPad)))
-				} else {
-					qubitLines[i] += wireColor(strings.Repeat("-", segmentSize))
-				}
+		}
+
+		// measurements mark the bit they write, conditioned gates the value each bit must hold
+		for k := 0; k < numCbits; k++ {
+			mark := ""
+			if _, ok := gate.Gate.(MeasureGate); ok && gate.Cbits[0] == k {
+				mark = "v"
+			} else if value, ok := gate.Condition.BitValue(k); ok {
+				mark = strconv.Itoa(value)
 			}
+			if mark == "" {
+				classicalLines[k] += wireColor(strings.Repeat("=", segmentSize))
+				continue
+			}
+			classicalLines[k] += fmt.Sprintf("%s%s%s", wireColor(strings.Repeat("=", leftPad+(width-1)/2)), gateColor(mark), wireColor(strings.Repeat("=", rightPad+width/2)))
 		}
 
 		for i := 0; i < numQubits; i++ {
 			qubitLines[i] += barrierColor("|")
 		}
+		for k := 0; k < numCbits; k++ {
+			classicalLines[k] += barrierColor("|")
+		}
 		barrierPositions = append(barrierPositions, len(qubitLines[0])-1)
 	}
 
-	for i := range qubitLines {
+	// the result feeds both the bloch vectors beside the wires and the table under them
+	result, runErr := c.ExecuteToBarrier(atBarrier)
+	blochNote := ""
+	if c.ShowBloch && runErr == nil {
+		vectors := make([]BlochVector, numQubits)
+		for i := range vectors {
+			vector, err := result.BlochVector(i)
+			if err != nil {
+				blochNote = fmt.Sprintf("Bloch vectors unavailable: %v", err)
+				vectors = nil
+				break
+			}
+			vectors[i] = vector
+		}
+		for i, vector := range vectors {
+			qubitLines[i] += fmt.Sprintf("  %s %s", gateColor(vector.Glyph()), qubitColor(vector.String()))
+		}
+	}
+
+	lines := append(qubitLines, classicalLines...)
+	for i := range lines {
 		sb.WriteString("\r")
-		sb.WriteString(qubitLines[i])
-		if i < len(qubitLines)-1 {
+		sb.WriteString(lines[i])
+		if i < len(lines)-1 {
 			sb.WriteString("\n")
 		}
 	}
@@ -278,23 +487,31 @@ func (c *Circuit) Draw(atBarrier int) error {
 		if i > 1 {
 			offset = len(strconv.Itoa(i)) - 1
 		}
-		sb.WriteString(fmt.Sprintf("%s%d", strings.Repeat(" ", len(string(c.Gates[i].Gate.Name()))+4-offset), i+1))
+		sb.WriteString(fmt.Sprintf("%s%d", strings.Repeat(" ", utf8.RuneCountInString(c.Gates[i].Gate.Name())+4-offset), i+1))
 	}
 
 	sb.WriteString("\n\n")
-	sb.WriteString(c.buildTable(atBarrier))
+	if blochNote != "" {
+		sb.WriteString("\r")
+		sb.WriteString(color.New(color.FgRed, color.Bold).SprintfFunc()(blochNote))
+		sb.WriteString("\n\n")
+	}
+	if runErr != nil {
+		sb.WriteString(fmt.Sprintf("Error executing circuit: %v\n", runErr))
+	} else {
+		sb.WriteString(c.buildTable(atBarrier, result))
+	}
 	sb.WriteString("\r")
 	fmt.Println(sb.String())
 	return nil
 }
 
-// executes the circuit up to a specific barrier n and returns the result
-func (c *Circuit) ExecuteToBarrier(atBarrier int) (Result, error) {
-	if atBarrier < 1 || atBarrier > len(c.Gates) {
-		return Result{}, ErrInvalidBarrier
-	}
-
+// number of wires the circuit spans
+func (c *Circuit) NumQubits() int {
 	numQubits := 0
+	if c.Initial != nil {
+		numQubits = c.Initial.NumWires()
+	}
 	for _, gate := range c.Gates {
 		for _, wire := range gate.Wires {
 			if wire >= numQubits {
@@ -302,34 +519,20 @@ func (c *Circuit) ExecuteToBarrier(atBarrier int) (Result, error) {
 			}
 		}
 	}
+	return numQubits
+}
 
-	stateVector := NewMatrix(1<<numQubits, 1)
-	stateVector.Data[0][0] = 1
-
-	// apply gates up to the barrier
-	gatesToApply := c.Gates
-	if atBarrier > 0 && atBarrier < len(c.Gates) {
-		gatesToApply = c.Gates[:atBarrier]
+// executes the circuit up to a specific barrier n on the circuit's backend and returns the result
+func (c *Circuit) ExecuteToBarrier(atBarrier int) (Result, error) {
+	if atBarrier < 1 || atBarrier > len(c.Gates) {
+		return Result{}, ErrInvalidBarrier
 	}
 
-	newStateVector, err := applyGates(stateVector, gatesToApply, numQubits)
+	backend, err := LookupBackend(c.Backend)
 	if err != nil {
 		return Result{}, err
 	}
-
-	labeledStateVector := make(map[string]complex128)
-	for i := 0; i < (1 << numQubits); i++ {
-		key := strings.Join(intToBitString(i, numQubits), "")
-		labeledStateVector[key] = newStateVector.Data[i][0]
-	}
-
-	result := Result{
-		StateVector:         labeledStateVector,
-		StateVectorSymbolic: SymbofyMap(labeledStateVector),
-		Probabilities:       Probabilities(labeledStateVector),
-	}
-
-	return result, nil
+	return backend.Run(c, atBarrier)
 }
 
 func Probabilities(stateVector map[string]complex128) map[string]float64 {
@@ -362,143 +565,168 @@ func intToBitString(value, length int) []string {
 	return bitString
 }
 
-func applyGates(stateVector Matrix, gates []CircuitGate, numQubits int) (Matrix, error) {
-	for _, gate := range gates {
-		fullGate := createFullGateMatrix(gate, numQubits)
-		if !fullGate.CanMultiply(&stateVector) {
-			return Matrix{}, errors.New("cannot multiply matrices")
-		}
-		stateVector = fullGate.MustMultiply(&stateVector)
-	}
-	return stateVector, nil
+// one row of the results table
+type tableState struct {
+	Key       string
+	Value     float64
+	State     string
+	Amplitude complex128
 }
 
-func createFullGateMatrix(gate CircuitGate, numQubits int) Matrix {
-	fullGate := Identity(1 << numQubits).Data()
-
-	if len(gate.Wires) == 1 {
-		fullGate = applyGateToQubit(gate.Gate.Data(), gate.Wires[0], numQubits)
-	} else if len(gate.Wires) == 2 {
-		fullGate = applyTwoQubitGate(gate.Gate.Data(), gate.Wires, numQubits)
-	} else if len(gate.Wires) == 3 {
-		fullGate = applyThreeQubitGate(gate.Gate.Data(), gate.Wires, numQubits)
+// the maxTableRows most likely basis states, more likely first and ties in binary
+// order, with how many others have any weight and how much they hold together. only
+// the listed ones get a bitstring label
+func tableStates(result Result) ([]tableState, int, float64) {
+	type candidate struct {
+		index int
+		key   string
+		p     float64
+	}
+	var candidates []candidate
+	hidden, hiddenWeight := 0, 0.0
+	prune := func(keep int) {
+		sort.Slice(candidates, func(i, j int) bool {
+			a, b := candidates[i], candidates[j]
+			if a.p != b.p {
+				return a.p > b.p
+			}
+			if a.key != b.key {
+				return a.key < b.key
+			}
+			return a.index < b.index
+		})
+		for _, dropped := range candidates[min(keep, len(candidates)):] {
+			hidden++
+			hiddenWeight += dropped.p
+		}
+		candidates = candidates[:min(keep, len(candidates))]
 	}
 
-	return fullGate
-}
-
-func applyGateToQubit(gate Matrix, qubit, numQubits int) Matrix {
-	if qubit == 0 {
-		identity := Identity(1 << (numQubits - 1)).Data()
-		return tensorGateMatrix(&gate, &identity)
-	} else if qubit == numQubits-1 {
-		identity := Identity(1 << (numQubits - 1)).Data()
-		return tensorGateMatrix(&identity, &gate)
+	if result.Probabilities == nil && result.Amplitudes != nil {
+		for i, amplitude := range result.Amplitudes {
+			if p := real(amplitude)*real(amplitude) + imag(amplitude)*imag(amplitude); p > 0 {
+				candidates = append(candidates, candidate{index: i, p: p})
+				if len(candidates) >= 4*maxTableRows {
+					prune(maxTableRows)
+				}
+			}
+		}
 	} else {
-		left := Identity(1 << qubit).Data()
-		right := Identity(1 << (numQubits - qubit - 1)).Data()
-		temp := tensorGateMatrix(&left, &gate)
-		return tensorGateMatrix(&temp, &right)
-	}
-}
-
-func applyTwoQubitGate(gate Matrix, wires []int, numQubits int) Matrix {
-	if len(wires) != 2 {
-		panic(ErrInvalidWireCount)
-	}
-
-	control, target := wires[0], wires[1]
-	if control > target {
-		control, target = target, control
+		for key, p := range result.Probabilities {
+			if p > 0 {
+				candidates = append(candidates, candidate{key: key, p: p})
+			}
+		}
 	}
+	prune(maxTableRows)
 
-	left := Identity(1 << control).Data()
-	middle := gate
-	right := Identity(1 << (numQubits - target - 1)).Data()
-
-	if target-control > 1 {
-		middleLeft := Identity(1 << (target - control - 1)).Data()
-		middle = tensorGateMatrix(&middleLeft, &middle)
+	numQubits := result.numQubits()
+	states := make([]tableState, len(candidates))
+	for i, c := range candidates {
+		if c.key == "" && result.Probabilities == nil {
+			amplitude := result.Amplitudes[c.index]
+			states[i] = tableState{Key: basisKey(c.index, numQubits), Value: c.p, State: Symbofy(amplitude), Amplitude: amplitude}
+			continue
+		}
+		states[i] = tableState{Key: c.key, Value: c.p, State: result.StateVectorSymbolic[c.key], Amplitude: result.StateVector[c.key]}
 	}
-
-	temp := tensorGateMatrix(&left, &middle)
-	return tensorGateMatrix(&temp, &right)
+	return states, hidden, hiddenWeight
 }
 
-func applyThreeQubitGate(gate Matrix, wires []int, numQubits int) Matrix {
-	if len(wires) != 3 {
-		panic(ErrInvalidWireCount)
-	}
-
-	control1, control2, target := wires[0], wires[1], wires[2]
-	if control1 > control2 {
-		control1, control2 = control2, control1
-	}
-	if control1 > target {
-		control1, target = target, control1
-	}
-	if control2 > target {
-		control2, target = target, control2
-	}
-
-	left := Identity(1 << control1).Data()
-	middle := gate
-	right := Identity(1 << (numQubits - target - 1)).Data()
-
-	if target-control2 > 1 {
-		middleLeft := Identity(1 << (target - control2 - 1)).Data()
-		middle = tensorGateMatrix(&middleLeft, &middle)
-	}
-	if control2-control1 > 1 {
-		middleLeft := Identity(1 << (control2 - control1 - 1)).Data()
-		middle = tensorGateMatrix(&middleLeft, &middle)
-	}
-
-	temp := tensorGateMatrix(&left, &middle)
-	return tensorGateMatrix(&temp, &right)
-}
-func (c *Circuit) buildTable(atBarrier int) string {
-	result, err := c.ExecuteToBarrier(atBarrier)
-	if err != nil {
-		return fmt.Sprintf("Error executing circuit: %v\n", err)
+func (c *Circuit) buildTable(atBarrier int, result Result) string {
+	var err error
+	if c.Shots > 0 {
+		result.Counts, err = c.SampleToBarrier(atBarrier, c.Shots, c.Seed)
+		if err != nil {
+			return fmt.Sprintf("Error sampling circuit: %v\n", err)
+		}
 	}
 
-	// collecting non-zero probs
-	var probabilities []struct {
-		Key   string
-		Value float64
-		State string
+	// the most likely basis states, capped so wide states don't print millions of rows
+	probabilities, hidden, hiddenWeight := tableStates(result)
+	// with mid-circuit measurements other branches can show up in the counts
+	listed := make(map[string]bool)
+	for _, p := range probabilities {
+		listed[p.Key] = true
 	}
-	for key, value := range result.Probabilities {
-		if value > 0 {
-			probabilities = append(probabilities, struct {
-				Key   string
-				Value float64
-				State string
-			}{
-				Key:   key,
-				Value: value,
-				State: result.StateVectorSymbolic[key],
-			})
+	for key := range result.Counts {
+		if !listed[key] {
+			probabilities = append(probabilities, tableState{Key: key, State: Symbofy(0)})
 		}
 	}
 
 	// sorting by binary, aka: 000 -> 001 -> 010 -> 011 -> 100 -> 101 -> 110 -> 111, etc.
+	// keys all have the same length so string order is binary order, even past 64 wires
 	sort.Slice(probabilities, func(i, j int) bool {
-		keyI, _ := strconv.ParseInt(probabilities[i].Key, 2, 64)
-		keyJ, _ := strconv.ParseInt(probabilities[j].Key, 2, 64)
-		return keyI < keyJ
+		return probabilities[i].Key < probabilities[j].Key
 	})
 
 	// reference phrase to base relative phase off of
 	var referencePhase float64
-	if len(probabilities) > 0 {
-		referencePhase = cmplx.Phase(result.StateVector[probabilities[0].Key])
+	for _, p := range probabilities {
+		if p.Value > 0 {
+			referencePhase = cmplx.Phase(p.Amplitude)
+			break
+		}
 	}
 
 	var sb strings.Builder
 	w := tabwriter.NewWriter(&sb, 0, 0, 1, ' ', 0)
 
+	if len(result.ClassicalRegister) > 0 {
+		bits := make([]string, len(result.ClassicalRegister))
+		for i, bit := range result.ClassicalRegister {
+			bits[i] = fmt.Sprintf("c%d=%d", i, bit)
+		}
+		sb.WriteString("\r")
+		sb.WriteString(color.New(color.FgRed, color.Bold).SprintfFunc()("Classical register: "))
+		sb.WriteString(color.New(color.FgWhite, color.Bold).SprintfFunc()(strings.Join(bits, " ")))
+		sb.WriteString("\n\n")
+	}
+	if c.sampledReset(atBarrier, result) {
+		sb.WriteString("\r")
+		sb.WriteString(color.New(color.FgRed, color.Bold).SprintfFunc()("Reset: "))
+		sb.WriteString(color.New(color.FgWhite, color.Bold).SprintfFunc()("one sampled branch, wires entangled with a reset wire collapse with it (--backend density traces it out)"))
+		sb.WriteString("\n\n")
+	}
+	if result.DensityMatrix != nil {
+		sb.WriteString("\r")
+		sb.WriteString(color.New(color.FgRed, color.Bold).SprintfFunc()("Purity: "))
+		sb.WriteString(color.New(color.FgWhite, color.Bold).SprintfFunc()(fmt.Sprintf("%.6f", result.Purity)))
+		sb.WriteString("\n\n")
+	}
+	if result.MaxBondDimension > 0 {
+		sb.WriteString("\r")
+		sb.WriteString(color.New(color.FgRed, color.Bold).SprintfFunc()("Truncation error: "))
+		sb.WriteString(color.New(color.FgWhite, color.Bold).SprintfFunc()(fmt.Sprintf("%.3e (widest bond %d)", result.TruncationError, result.MaxBondDimension)))
+		sb.WriteString("\n\n")
+	}
+	if result.Stabilizers != nil {
+		sb.WriteString("\r")
+		sb.WriteString(color.New(color.FgRed, color.Bold).SprintfFunc()("Stabilizers: "))
+		if len(result.Stabilizers) > maxListedStabilizers {
+			sb.WriteString(color.New(color.FgWhite, color.Bold).SprintfFunc()(fmt.Sprintf("%d generators, too wide to list", len(result.Stabilizers))))
+			sb.WriteString("\n")
+		} else {
+			sb.WriteString("\n")
+			for _, generator := range result.Stabilizers {
+				sb.WriteString("\r  ")
+				sb.WriteString(color.New(color.FgWhite, color.Bold).SprintfFunc()(generator))
+				sb.WriteString("\n")
+			}
+		}
+		if support, ok := result.sampler.(*stabilizerSupport); ok && result.Probabilities == nil {
+			sb.WriteString("\r")
+			sb.WriteString(color.New(color.FgRed, color.Bold).SprintfFunc()("Outcomes: "))
+			sb.WriteString(color.New(color.FgWhite, color.Bold).SprintfFunc()(fmt.Sprintf("2^%d equally likely bitstrings, too many to list (sample them with --shots)", len(support.basis))))
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	// mixed states have no amplitudes, only the diagonal of ρ
+	hasAmplitudes := result.StateVector != nil || result.Amplitudes != nil
+
 	headerFmt := color.New(color.FgRed, color.Bold).SprintfFunc()
 	columnFmt := color.New(color.FgWhite, color.Bold).SprintfFunc()
 
@@ -508,6 +736,18 @@ func (c *Circuit) buildTable(atBarrier int) string {
 		"Probability ",
 		"Relative phase ",
 	}
+	if !hasAmplitudes {
+		headers = []string{
+			"State ",
+			"Probability ",
+		}
+	}
+	if result.ConfidenceIntervals != nil {
+		headers = append(headers, "95%% CI ")
+	}
+	if result.Counts != nil {
+		headers = append(headers, "Counts ")
+	}
 
 	rows := [][]string{
 		headers,
@@ -515,13 +755,25 @@ func (c *Circuit) buildTable(atBarrier int) string {
 
 	for _, p := range probabilities {
 		truncatedValue := fmt.Sprintf("%.2f%s", p.Value*100, "%%")
-		phaseValue := fmt.Sprintf("%.9f", cmplx.Phase(result.StateVector[p.Key])-referencePhase)
+		phaseValue := fmt.Sprintf("%.9f", cmplx.Phase(p.Amplitude)-referencePhase)
 		row := []string{
 			p.Key,
 			p.State,
 			truncatedValue,
 			phaseValue,
 		}
+		if !hasAmplitudes {
+			row = []string{
+				p.Key,
+				truncatedValue,
+			}
+		}
+		if result.ConfidenceIntervals != nil {
+			row = append(row, fmt.Sprintf("%.2f%s", result.ConfidenceIntervals[p.Key]*100, "%%"))
+		}
+		if result.Counts != nil {
+			row = append(row, strconv.Itoa(result.Counts[p.Key]))
+		}
 		rows = append(rows, row)
 	}
 
@@ -560,5 +812,49 @@ func (c *Circuit) buildTable(atBarrier int) string {
 	}
 
 	w.Flush()
+	if hidden > 0 {
+		sb.WriteString("\r")
+		sb.WriteString(headerFmt("Not shown: "))
+		sb.WriteString(columnFmt(fmt.Sprintf("%d less likely states holding %.2f%%", hidden, hiddenWeight*100)))
+		sb.WriteString("\n")
+	}
+	if c.ShowEntanglement {
+		sb.WriteString(entanglementPanel(result))
+	}
+	return sb.String()
+}
+
+// each wire's entropy against the rest and the purity of its reduced state
+func entanglementPanel(result Result) string {
+	headerFmt := color.New(color.FgRed, color.Bold).SprintfFunc()
+	columnFmt := color.New(color.FgWhite, color.Bold).SprintfFunc()
+
+	var sb strings.Builder
+	sb.WriteString("\r\n")
+	sb.WriteString(headerFmt("Entanglement: "))
+	numQubits := result.numQubits()
+	if numQubits > maxListedEntropyWires {
+		sb.WriteString(columnFmt(fmt.Sprintf("%d wires, too wide to list", numQubits)))
+		sb.WriteString("\n")
+		return sb.String()
+	}
+	sb.WriteString("\n")
+	for wire := 0; wire < numQubits; wire++ {
+		reduced, err := result.ReducedDensityMatrix([]int{wire})
+		if err != nil {
+			sb.WriteString("\r  ")
+			sb.WriteString(columnFmt(fmt.Sprintf("unavailable: %v", err)))
+			sb.WriteString("\n")
+			return sb.String()
+		}
+		entropy, err := result.Entropy([]int{wire})
+		if err != nil {
+			entropy = VonNeumannEntropy(reduced)
+		}
+		sb.WriteString("\r  ")
+		sb.WriteString(headerFmt(fmt.Sprintf("q%-4d", wire)))
+		sb.WriteString(columnFmt(fmt.Sprintf("entropy %.4f  purity %.4f", entropy, Purity(reduced))))
+		sb.WriteString("\n")
+	}
 	return sb.String()
 }
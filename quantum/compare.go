@@ -0,0 +1,353 @@
+package quantum
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strconv"
+)
+
+// distances between two circuit states, to check that a rewritten circuit still
+// prepares the same state
+
+// how close two results are
+type Comparison struct {
+	// |⟨ψ|φ⟩|² for pure states, 1 when they're equal up to global phase
+	Fidelity float64
+	// half the trace norm of ρ - σ, 0 when the states are equal
+	TraceDistance float64
+	// half the summed absolute difference between the outcome distributions
+	TotalVariation float64
+}
+
+// fidelity, trace distance and total variation distance between two results over the same wires
+func Compare(a, b Result) (Comparison, error) {
+	if a.numQubits() != b.numQubits() {
+		return Comparison{}, fmt.Errorf("%w: states span %d and %d wires", ErrInvalidWireCount, a.numQubits(), b.numQubits())
+	}
+	fidelity, err := Fidelity(a, b)
+	if err != nil {
+		return Comparison{}, err
+	}
+	distance, err := TraceDistance(a, b)
+	if err != nil {
+		return Comparison{}, err
+	}
+	p, q := a.probabilityMap(), b.probabilityMap()
+	if !completeProbabilities(p) || !completeProbabilities(q) {
+		return Comparison{}, ErrComparisonUnsupported
+	}
+	return Comparison{
+		Fidelity:       fidelity,
+		TraceDistance:  distance,
+		TotalVariation: TotalVariationDistance(p, q),
+	}, nil
+}
+
+// the nonzero amplitudes of a result by bitstring, from its map or its amplitude slice
+func (r Result) amplitudeMap() map[string]complex128 {
+	if r.StateVector != nil || r.Amplitudes == nil {
+		return r.StateVector
+	}
+	amplitudes := make(map[string]complex128)
+	numQubits := r.numQubits()
+	for i, amplitude := range r.Amplitudes {
+		if amplitude != 0 {
+			amplitudes[basisKey(i, numQubits)] = amplitude
+		}
+	}
+	return amplitudes
+}
+
+// the result's probabilities, labelled from its amplitude slice when it has no map
+func (r Result) probabilityMap() map[string]float64 {
+	if r.Probabilities != nil || r.Amplitudes == nil {
+		return r.Probabilities
+	}
+	return Probabilities(r.amplitudeMap())
+}
+
+// compares circuit a at barrier atA with b at atB. a circuit that measures or resets
+// before its barrier is compared by ρ averaged over every outcome, since the one
+// branch a run samples depends on where in the circuit the measurement sits
+func CompareCircuits(a, b *Circuit, atA, atB int) (Comparison, error) {
+	resultA, err := a.comparisonResult(atA)
+	if err != nil {
+		return Comparison{}, fmt.Errorf("circuit A: %w", err)
+	}
+	resultB, err := b.comparisonResult(atB)
+	if err != nil {
+		return Comparison{}, fmt.Errorf("circuit B: %w", err)
+	}
+	return Compare(resultA, resultB)
+}
+
+func (c *Circuit) comparisonResult(atBarrier int) (Result, error) {
+	if atBarrier < 1 || atBarrier > len(c.Gates) {
+		return Result{}, ErrInvalidBarrier
+	}
+	for _, gate := range c.Gates[:atBarrier] {
+		if measures(gate.Gate) {
+			return c.averageOutcomes(atBarrier)
+		}
+	}
+	return c.ExecuteToBarrier(atBarrier)
+}
+
+// the circuit's state given one classical register value, weighted by its chance
+type densityBranch struct {
+	rho       Matrix
+	classical []int
+	weight    float64
+}
+
+// runs the circuit up to barrier n on ρ, keeping every measurement outcome instead of
+// sampling one. branches are merged by classical register, so gates conditioned on it
+// still act on exactly the branches where their condition holds
+func (c *Circuit) averageOutcomes(atBarrier int) (Result, error) {
+	numQubits := c.NumQubits()
+	if maxDensity := (maxWires+1)/2 - 1; numQubits > maxDensity+1 {
+		return Result{}, fmt.Errorf("%w: averaging measurement outcomes needs ρ, max: %d", ErrTooManyWires, maxDensity)
+	}
+
+	branches := []*densityBranch{{c.initialDensity(numQubits), make([]int, c.NumCbits()), 1}}
+	for i := 0; i < atBarrier; i++ {
+		gate := c.Gates[i]
+		if _, ok := gate.Gate.(MeasureGate); !ok {
+			for _, b := range branches {
+				if err := c.runDensityGates(&b.rho, numQubits, b.classical, i, i+1); err != nil {
+					return Result{}, err
+				}
+			}
+			continue
+		}
+
+		var next []*densityBranch
+		byRegister := make(map[string]*densityBranch)
+		// adds ρ with weight w, or mixes it into the branch with the same register
+		add := func(rho Matrix, classical []int, w float64) {
+			key := fmt.Sprint(classical)
+			b, ok := byRegister[key]
+			if !ok {
+				b = &densityBranch{copyMatrix(rho), append([]int(nil), classical...), w}
+				byRegister[key] = b
+				next = append(next, b)
+				return
+			}
+			total := b.weight + w
+			for r := range b.rho.Data {
+				for col := range b.rho.Data[r] {
+					b.rho.Data[r][col] = (complex(b.weight, 0)*b.rho.Data[r][col] + complex(w, 0)*rho.Data[r][col]) / complex(total, 0)
+				}
+			}
+			b.weight = total
+		}
+
+		wire, cbit := gate.Wires[0], gate.Cbits[0]
+		for _, b := range branches {
+			if !gate.Condition.Holds(b.classical) {
+				add(b.rho, b.classical, b.weight)
+				continue
+			}
+			p1 := densityProbabilityOfOne(b.rho, numQubits, wire)
+			for outcome, p := range []float64{1 - p1, p1} {
+				// a forced outcome keeps only its branch
+				if forced, ok := c.Outcomes[i]; (ok && forced != outcome) || p < 1e-12 {
+					continue
+				}
+				projected := copyMatrix(b.rho)
+				projectDensityWire(&projected, numQubits, wire, outcome, p)
+				flip := c.Noise.readoutFlip(wire, outcome)
+				for recorded, q := range map[int]float64{outcome: 1 - flip, 1 - outcome: flip} {
+					if q > 0 {
+						classical := append([]int(nil), b.classical...)
+						classical[cbit] = recorded
+						add(projected, classical, b.weight*p*q)
+					}
+				}
+			}
+		}
+		if len(next) == 0 {
+			return Result{}, fmt.Errorf("gate %d: %w", i+1, ErrImpossibleOutcome)
+		}
+		if len(next) > maxComparedBranches {
+			return Result{}, fmt.Errorf("%w: more than %d classical registers to average over", ErrComparisonUnsupported, maxComparedBranches)
+		}
+		branches = next
+	}
+
+	// forced outcomes leave less than all the weight, the rest is renormalised away
+	rho := NewMatrix(1<<numQubits, 1<<numQubits)
+	total := 0.0
+	likeliest := branches[0]
+	for _, b := range branches {
+		total += b.weight
+		if b.weight > likeliest.weight {
+			likeliest = b
+		}
+	}
+	for _, b := range branches {
+		for r := range rho.Data {
+			for col := range rho.Data[r] {
+				rho.Data[r][col] += complex(b.weight/total, 0) * b.rho.Data[r][col]
+			}
+		}
+	}
+	return densityResult(rho, numQubits, likeliest.classical), nil
+}
+
+// ½ Σ |p(x) - q(x)| over every outcome either distribution has
+func TotalVariationDistance(p, q map[string]float64) float64 {
+	total := 0.0
+	for key, value := range p {
+		total += math.Abs(value - q[key])
+	}
+	for key, value := range q {
+		if _, ok := p[key]; !ok {
+			total += value
+		}
+	}
+	return total / 2
+}
+
+// the result's state as amplitudes when it has all of them, or as ρ
+func (r Result) comparableState() (map[string]complex128, *Matrix, error) {
+	if r.DensityMatrix != nil {
+		return nil, r.DensityMatrix, nil
+	}
+	if amplitudes := r.amplitudeMap(); complete(amplitudes) {
+		return amplitudes, nil, nil
+	}
+	// narrow enough results rebuild ρ from whatever the backend can answer
+	wires := make([]int, r.numQubits())
+	for i := range wires {
+		wires[i] = i
+	}
+	rho, err := r.ReducedDensityMatrix(wires)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrComparisonUnsupported, err)
+	}
+	return nil, &rho, nil
+}
+
+// |⟨ψ|φ⟩|² between pure states, ⟨ψ|σ|ψ⟩ when one is mixed and (Tr √(√ρ σ √ρ))² in general
+func Fidelity(a, b Result) (float64, error) {
+	psi, rho, err := a.comparableState()
+	if err != nil {
+		return 0, err
+	}
+	phi, sigma, err := b.comparableState()
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case psi != nil && phi != nil:
+		overlap := complex(0, 0)
+		for key, amplitude := range psi {
+			overlap += cmplx.Conj(amplitude) * phi[key]
+		}
+		return real(overlap)*real(overlap) + imag(overlap)*imag(overlap), nil
+	case psi != nil:
+		return mixedOverlap(psi, *sigma), nil
+	case phi != nil:
+		return mixedOverlap(phi, *rho), nil
+	}
+
+	// √ρ σ √ρ is positive semidefinite, the root of its trace is the sum of its eigenvalues' roots
+	root := psdSqrt(*rho)
+	m := root.MustMultiply(sigma)
+	m = m.MustMultiply(&root)
+	sum := 0.0
+	for _, value := range psdEigenvalues(m.Data) {
+		sum += math.Sqrt(math.Max(0, value))
+	}
+	return math.Min(1, sum*sum), nil
+}
+
+// half the trace norm of ρ - σ, which is √(1 - F) between pure states
+func TraceDistance(a, b Result) (float64, error) {
+	psi, rho, err := a.comparableState()
+	if err != nil {
+		return 0, err
+	}
+	phi, sigma, err := b.comparableState()
+	if err != nil {
+		return 0, err
+	}
+	if psi != nil && phi != nil {
+		fidelity, err := Fidelity(a, b)
+		if err != nil {
+			return 0, err
+		}
+		return math.Sqrt(math.Max(0, 1-fidelity)), nil
+	}
+
+	if psi != nil {
+		m := densityFromAmplitudes(psi, sigma.Rows)
+		rho = &m
+	}
+	if phi != nil {
+		m := densityFromAmplitudes(phi, rho.Rows)
+		sigma = &m
+	}
+	// ρ - σ is Hermitian so its singular values are the magnitudes of its eigenvalues
+	difference := make([][]complex128, rho.Rows)
+	for i := range difference {
+		difference[i] = make([]complex128, rho.Cols)
+		for j := range difference[i] {
+			difference[i][j] = rho.Data[i][j] - sigma.Data[i][j]
+		}
+	}
+	_, values, _ := svd(difference)
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / 2, nil
+}
+
+// ⟨ψ|σ|ψ⟩
+func mixedOverlap(psi map[string]complex128, sigma Matrix) float64 {
+	indices := make(map[int]complex128, len(psi))
+	for key, amplitude := range psi {
+		index, _ := strconv.ParseInt(key, 2, 64)
+		indices[int(index)] = amplitude
+	}
+	sum := complex(0, 0)
+	for i, a := range indices {
+		for j, b := range indices {
+			sum += cmplx.Conj(a) * sigma.Data[i][j] * b
+		}
+	}
+	return real(sum)
+}
+
+// |ψ⟩⟨ψ| as a dim x dim matrix
+func densityFromAmplitudes(psi map[string]complex128, dim int) Matrix {
+	rho := NewMatrix(dim, dim)
+	for keyA, a := range psi {
+		i, _ := strconv.ParseInt(keyA, 2, 64)
+		for keyB, b := range psi {
+			j, _ := strconv.ParseInt(keyB, 2, 64)
+			rho.Data[i][j] = a * cmplx.Conj(b)
+		}
+	}
+	return rho
+}
+
+// √ρ = U √S U† from ρ = U S U†, which the SVD of a positive semidefinite matrix is
+func psdSqrt(rho Matrix) Matrix {
+	u, s, _ := svd(rho.Data)
+	root := NewMatrix(rho.Rows, rho.Cols)
+	for i := range root.Data {
+		for j := range root.Data[i] {
+			sum := complex(0, 0)
+			for k, value := range s {
+				sum += u[i][k] * complex(math.Sqrt(value), 0) * cmplx.Conj(u[j][k])
+			}
+			root.Data[i][j] = sum
+		}
+	}
+	return root
+}
@@ -0,0 +1,169 @@
+package quantum
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+	"strings"
+	"testing"
+)
+
+func TestComparePureStates(t *testing.T) {
+	tests := []struct {
+		a, b                    []string
+		fidelity, distance, tvd float64
+	}{
+		{[]string{"h0", "cnot0,1"}, []string{"h1", "cnot1,0"}, 1, 0, 0},
+		// ZXZ = -X only differs by a global phase
+		{[]string{"z0", "x0", "z0"}, []string{"x0"}, 1, 0, 0},
+		{[]string{"i0"}, []string{"x0"}, 0, 1, 1},
+		{[]string{"i0"}, []string{"h0"}, 0.5, math.Sqrt(0.5), 0.5},
+		// same distribution, different phase
+		{[]string{"h0"}, []string{"h0", "z0"}, 0, 1, 0},
+	}
+	for _, tt := range tests {
+		got, err := Compare(runCircuit(t, tt.a, BackendStateVector), runCircuit(t, tt.b, BackendStateVector))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(got.Fidelity-tt.fidelity) > 1e-9 || math.Abs(got.TraceDistance-tt.distance) > 1e-9 || math.Abs(got.TotalVariation-tt.tvd) > 1e-9 {
+			t.Errorf("%v vs %v: got %+v, want %v %v %v", tt.a, tt.b, got, tt.fidelity, tt.distance, tt.tvd)
+		}
+	}
+}
+
+func TestCompareMixedStates(t *testing.T) {
+	a := runCircuit(t, []string{"h0", "depol0(0.2)"}, BackendDensity)
+	b := runCircuit(t, []string{"ry0(1)", "depol0(0.1)"}, BackendDensity)
+	got, err := Compare(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// single qubit closed forms: F = Tr ρσ + 2 √(det ρ det σ) and half the Bloch distance
+	rho, sigma := a.DensityMatrix.Data, b.DensityMatrix.Data
+	overlap := real(rho[0][0]*sigma[0][0] + rho[0][1]*sigma[1][0] + rho[1][0]*sigma[0][1] + rho[1][1]*sigma[1][1])
+	det := func(m [][]complex128) float64 { return real(m[0][0]*m[1][1] - m[0][1]*m[1][0]) }
+	fidelity := overlap + 2*math.Sqrt(det(rho)*det(sigma))
+	va, _ := a.BlochVector(0)
+	vb, _ := b.BlochVector(0)
+	distance := math.Sqrt((va.X-vb.X)*(va.X-vb.X)+(va.Y-vb.Y)*(va.Y-vb.Y)+(va.Z-vb.Z)*(va.Z-vb.Z)) / 2
+	if math.Abs(got.Fidelity-fidelity) > 1e-9 || math.Abs(got.TraceDistance-distance) > 1e-9 {
+		t.Errorf("got %+v, want fidelity %v and trace distance %v", got, fidelity, distance)
+	}
+
+	// a pure state against a mixed one, in either order and either representation
+	pure := runCircuit(t, []string{"ry0(1)"}, BackendStateVector)
+	pureDensity := runCircuit(t, []string{"ry0(1)"}, BackendDensity)
+	for _, pair := range [][2]Result{{pure, a}, {a, pure}} {
+		want, err := Compare(pureDensity, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Compare(pair[0], pair[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(got.Fidelity-want.Fidelity) > 1e-9 || math.Abs(got.TraceDistance-want.TraceDistance) > 1e-9 {
+			t.Errorf("pure vs mixed: got %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestCompareAcrossBackends(t *testing.T) {
+	gates := []string{"h0", "s0", "cnot0,1", "h2", "cz1,2"}
+	want := runCircuit(t, gates, BackendStateVector)
+	for _, backend := range []string{BackendDensity, BackendStabilizer, BackendMPS} {
+		got, err := Compare(want, runCircuit(t, gates, backend))
+		if err != nil {
+			t.Fatalf("%s: %v", backend, err)
+		}
+		if math.Abs(got.Fidelity-1) > 1e-9 || got.TraceDistance > 1e-6 || got.TotalVariation > 1e-9 {
+			t.Errorf("%s: got %+v, want identical states", backend, got)
+		}
+	}
+
+	c, _ := NewCircuit(gates)
+	c.Backend = BackendTrajectory
+	c.Trajectories = 2
+	trajectory, err := c.ExecuteToBarrier(len(gates))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Compare(want, trajectory); !errors.Is(err, ErrComparisonUnsupported) {
+		t.Errorf("expected ErrComparisonUnsupported, got %v", err)
+	}
+
+	if _, err := Compare(want, runCircuit(t, []string{"h0"}, BackendStateVector)); !errors.Is(err, ErrInvalidWireCount) {
+		t.Errorf("expected ErrInvalidWireCount, got %v", err)
+	}
+}
+
+func TestPSDSqrt(t *testing.T) {
+	result := runCircuit(t, []string{"ry0(0.7)", "depol0(0.3)", "h1", "crx1,0(0.5)"}, BackendDensity)
+	root := psdSqrt(*result.DensityMatrix)
+	square := root.MustMultiply(&root)
+	for i := range square.Data {
+		for j := range square.Data[i] {
+			if cmplx.Abs(square.Data[i][j]-result.DensityMatrix.Data[i][j]) > 1e-9 {
+				t.Fatalf("√ρ√ρ differs from ρ at %d,%d", i, j)
+			}
+		}
+	}
+	if d := TotalVariationDistance(map[string]float64{"0": 0.5, "1": 0.5}, map[string]float64{"0": 1}); d != 0.5 {
+		t.Errorf("TVD = %v, want 0.5", d)
+	}
+}
+
+func TestCompareMeasuredCircuits(t *testing.T) {
+	for _, pair := range [][2]string{
+		// the same measurement at a different gate index
+		{"x1 h0 m0->0", "h0 m0->0 x1"},
+		// feed-forward on the register against the entangled equivalent
+		{"h0 m0->0 x1?c0==1", "h0 cnot0,1 m0->0"},
+		// reset traces the partner out, the same as measuring and flipping it back
+		{"h0 cnot0,1 reset1", "h0 cnot0,1 m1->0 x1?c0==1"},
+		{"h0 cnot0,1 reset1", "h0 m0->0 i1"},
+	} {
+		for seed := int64(0); seed < 20; seed++ {
+			a, err := NewCircuit(strings.Split(pair[0], " "))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := NewCircuit(strings.Split(pair[1], " "))
+			if err != nil {
+				t.Fatal(err)
+			}
+			a.Seed, b.Seed = seed, seed
+			a.Backend, b.Backend = BackendStateVector, BackendStateVector
+			comparison, err := CompareCircuits(&a, &b, len(a.Gates), len(b.Gates))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(comparison.Fidelity-1) > 1e-9 || comparison.TraceDistance > 1e-9 || comparison.TotalVariation > 1e-9 {
+				t.Fatalf("%q and %q, seed %d: %+v", pair[0], pair[1], seed, comparison)
+			}
+		}
+	}
+
+	// a measured |+⟩ is the mixed state, half as close to |+⟩ as |+⟩ is
+	a, _ := NewCircuit([]string{"h0", "m0->0"})
+	b, _ := NewCircuit([]string{"h0", "i0"})
+	comparison, err := CompareCircuits(&a, &b, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(comparison.Fidelity-0.5) > 1e-9 {
+		t.Errorf("fidelity %v, want 0.5", comparison.Fidelity)
+	}
+
+	// a forced outcome keeps only its branch
+	a.SetOutcome(1, 1)
+	b, _ = NewCircuit([]string{"x0", "i0"})
+	if comparison, err = CompareCircuits(&a, &b, 2, 2); err != nil || math.Abs(comparison.Fidelity-1) > 1e-9 {
+		t.Errorf("forced outcome: %+v, %v", comparison, err)
+	}
+	if _, err := CompareCircuits(&a, &b, 3, 2); !errors.Is(err, ErrInvalidBarrier) {
+		t.Errorf("expected ErrInvalidBarrier, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package quantum
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// parses the part after "?" of a conditioned gate: bitStr is empty when the whole
+// register is compared, valueStr may be decimal or 0b/0x prefixed
+func parseCondition(bitStr, valueStr string) (*Condition, error) {
+	value, err := strconv.ParseInt(valueStr, 0, 64)
+	if err != nil || value < 0 {
+		return nil, ErrInvalidCondition
+	}
+	if bitStr == "" {
+		return &Condition{Bit: -1, Value: int(value)}, nil
+	}
+
+	bit, err := strconv.Atoi(bitStr)
+	if err != nil || value > 1 {
+		return nil, ErrInvalidCondition
+	}
+	if bit > maxParsedWire() {
+		return nil, fmt.Errorf("%w, max: %d", ErrTooManyCbits, maxParsedWire())
+	}
+	return &Condition{Bit: bit, Value: int(value)}, nil
+}
+
+// true if the classical register satisfies the condition, a nil condition always holds
+func (cond *Condition) Holds(classical []int) bool {
+	if cond == nil {
+		return true
+	}
+	if cond.Bit >= 0 {
+		return cond.Bit < len(classical) && classical[cond.Bit] == cond.Value
+	}
+
+	// cbit 0 is the least significant bit of the register value, a set bit past what
+	// the value holds can't match it
+	value := 0
+	for i, bit := range classical {
+		if i >= maxRegisterBits {
+			if bit != 0 {
+				return false
+			}
+			continue
+		}
+		value |= bit << i
+	}
+	return value == cond.Value
+}
+
+// value cbit k must hold for the condition to pass, ok is false if k is not compared
+func (cond *Condition) BitValue(k int) (int, bool) {
+	if cond == nil {
+		return 0, false
+	}
+	if cond.Bit >= 0 {
+		return cond.Value, cond.Bit == k
+	}
+	return (cond.Value >> k) & 1, true
+}
+
+// ?c== compares the register as one int64, which holds 63 bits
+func checkRegisterConditions(c *Circuit) error {
+	numCbits := c.NumCbits()
+	if numCbits <= maxRegisterBits {
+		return nil
+	}
+	for i, gate := range c.Gates {
+		if gate.Condition != nil && gate.Condition.Bit < 0 {
+			return fmt.Errorf("%w: gate %d compares all %d classical bits, at most %d fit one value", ErrInvalidCondition, i+1, numCbits, maxRegisterBits)
+		}
+	}
+	return nil
+}
+
+// a cbit no measurement writes stays 0 and unlisted, so a condition reading it
+// would never see the value it asks for
+func checkConditionBits(c *Circuit) error {
+	written := make(map[int]bool)
+	for _, gate := range c.Gates {
+		for _, cbit := range gate.Cbits {
+			written[cbit] = true
+		}
+	}
+	for i, gate := range c.Gates {
+		cond := gate.Condition
+		if cond == nil {
+			continue
+		}
+		if cond.Bit >= 0 && !written[cond.Bit] {
+			return fmt.Errorf("%w: gate %d reads c%d, which no measurement writes", ErrInvalidCondition, i+1, cond.Bit)
+		}
+		for k := 0; cond.Bit < 0 && cond.Value>>k != 0; k++ {
+			if cond.Value>>k&1 == 1 && !written[k] {
+				return fmt.Errorf("%w: gate %d needs c%d set, which no measurement writes", ErrInvalidCondition, i+1, k)
+			}
+		}
+	}
+	return nil
+}
+
+// highest cbit the condition reads, -1 for the whole register
+func (cond *Condition) maxBit() int {
+	if cond == nil {
+		return -1
+	}
+	return cond.Bit
+}
@@ -0,0 +1,117 @@
+package quantum
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+// teleports rx(pi/3)|0⟩ from wire 0 to wire 2 for every measurement branch
+func TestTeleportation(t *testing.T) {
+	circuit, err := NewCircuit(strings.Split("rx0(pi/3) h1 cnot1,2 cnot0,1 h0 m0->0 m1->1 x2?c1==1 z2?c0==1", " "))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := math.Pow(math.Sin(math.Pi/6), 2)
+	for _, outcomes := range [][2]int{{0, 0}, {0, 1}, {1, 0}, {1, 1}} {
+		circuit.SetOutcome(5, outcomes[0])
+		circuit.SetOutcome(6, outcomes[1])
+		result, err := circuit.ExecuteToBarrier(len(circuit.Gates))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		p1 := 0.0
+		for key, p := range result.Probabilities {
+			if key[2] == '1' {
+				p1 += p
+			}
+		}
+		if math.Abs(p1-want) > 1e-9 {
+			t.Errorf("outcomes %v: P(wire 2 = 1) = %v, want %v", outcomes, p1, want)
+		}
+	}
+}
+
+func TestConditionParsing(t *testing.T) {
+	tests := []struct {
+		gate string
+		want Condition
+	}{
+		{"x1?c0==1", Condition{Bit: 0, Value: 1}},
+		{"z2?c=0b10", Condition{Bit: -1, Value: 2}},
+		{"rx0(pi/2)?c3=0", Condition{Bit: 3, Value: 0}},
+	}
+	for _, tt := range tests {
+		gate, err := nameToCircuitGate(tt.gate)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.gate, err)
+		}
+		if gate.Condition == nil || *gate.Condition != tt.want {
+			t.Errorf("%s: condition %+v, want %+v", tt.gate, gate.Condition, tt.want)
+		}
+	}
+
+	if _, err := nameToCircuitGate("x0?c0==2"); !errors.Is(err, ErrInvalidCondition) {
+		t.Errorf("expected ErrInvalidCondition, got %v", err)
+	}
+}
+
+func TestRegisterCondition(t *testing.T) {
+	circuit, err := NewCircuit(strings.Split("x1 m0->0 m1->1 x2?c=0b10 x3?c=0b01", " "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := circuit.ExecuteToBarrier(len(circuit.Gates))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Probabilities["0110"] < 1-1e-9 {
+		t.Errorf("only the c=0b10 gate should fire, got %v", result.Probabilities)
+	}
+}
+
+func TestConditionsNeedMeasuredBits(t *testing.T) {
+	for _, gates := range []string{"x0?c=1", "x0?c0==1", "x0?c0==0", "m0->0 x1?c1==1", "m0->0 x1?c=0b10"} {
+		if _, err := NewCircuit(strings.Split(gates, " ")); !errors.Is(err, ErrInvalidCondition) {
+			t.Errorf("%s: expected ErrInvalidCondition, got %v", gates, err)
+		}
+	}
+
+	// c=0 holds with nothing measured, and only the set bits of a value need a measurement
+	for _, gates := range []string{"x0?c=0", "m0->1 x1?c=0b10", "x1 x1?c0==1 m1->0"} {
+		if _, err := NewCircuit(strings.Split(gates, " ")); err != nil {
+			t.Errorf("%s: %v", gates, err)
+		}
+	}
+}
+
+func TestConditionBitsAreBounded(t *testing.T) {
+	if _, err := nameToCircuitGate("x0?c999999999==1"); !errors.Is(err, ErrTooManyCbits) {
+		t.Errorf("expected ErrTooManyCbits, got %v", err)
+	}
+
+	// 63 bits still fit one value, 71 don't
+	if _, err := NewCircuit([]string{"m0->62", "x1?c=0"}); err != nil {
+		t.Errorf("63 bit register: %v", err)
+	}
+	if _, err := NewCircuit([]string{"m0->70", "x1?c=0"}); !errors.Is(err, ErrInvalidCondition) {
+		t.Errorf("expected ErrInvalidCondition for a 71 bit register, got %v", err)
+	}
+	if _, err := NewCircuit([]string{"m0->70", "x1?c70==1"}); err != nil {
+		t.Errorf("single bit conditions don't care about the width: %v", err)
+	}
+
+	// a bit past the value's width is never part of a match
+	condition := &Condition{Bit: -1, Value: 0}
+	classical := make([]int, 70)
+	if !condition.Holds(classical) {
+		t.Error("an all zero register should match 0")
+	}
+	classical[64] = 1
+	if condition.Holds(classical) {
+		t.Error("bit 64 set shouldn't match 0")
+	}
+}
@@ -6,24 +6,120 @@ import (
 	"regexp"
 )
 
+// simulators a circuit can run on
+const (
+	BackendStateVector = "statevector"
+	BackendDensity     = "density"
+	BackendTrajectory  = "trajectory"
+	BackendStabilizer  = "stabilizer"
+	BackendMPS         = "mps"
+)
+
 var (
 	//! constants
-	// matches gate and optional wires with delimiter "," and allows some gates to have n optional arguments in () right after wire delcariations
-	gateWireRegex = regexp.MustCompile(`^([a-z]+)(\d+(?:,\d+)*)?(?:\((.*)\))?$`)
-	// max wires
-	maxWires = 9
+	// matches gate and optional wires with delimiter "," and allows some gates to have n optional arguments in () right after wire delcariations,
+	// optionally followed by a classical condition on one bit (?c0==1) or the whole register (?c=0b10)
+	gateWireRegex = regexp.MustCompile(`^([a-z]+)(\d+(?:,\d+)*)?(?:\((.*)\))?(?:\?c(\d*)==?(\w+))?$`)
+	// valid names for registered gates and channels
+	gateNameRegex = regexp.MustCompile(`^[a-z]+$`)
+	// valid names for registered backends
+	backendNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+	// c(0,~1) in front of a gate, ~ marks a control that acts on |0⟩
+	controlListRegex = regexp.MustCompile(`^c\(([^)]*)\)(.+)$`)
+	// ctrl@, ctrl(2)@ or negctrl@ in front of a gate, the controls are its first wires
+	controlPrefixRegex = regexp.MustCompile(`^(ctrl|negctrl)(?:\((\d+)\))?@(.+)$`)
+	// the wires of a gate example like crx0,1(pi/2)
+	exampleWiresRegex = regexp.MustCompile(`^[a-z]+(\d+(?:,\d+)*)`)
+	// matches a measurement of a wire into a classical bit, e.g. m0->1
+	measureRegex = regexp.MustCompile(`^m(\d+)->(\d+)$`)
+	// max wire index, each extra wire doubles the state vector (2^25 amplitudes is ~512MB)
+	maxWires = 24
+	// state vector results label every amplitude up to this many wires, wider ones only
+	// keep Result.Amplitudes
+	maxListedWires = 12
+	// most basis states the results table prints, the most likely ones
+	maxTableRows = 64
+	// max wire index for the stabilizer backend, which grows polynomially
+	maxStabilizerWires = 9_999
+	// widest gate whose noise model error is one depolarizing channel on all its wires,
+	// wider gates get one on each wire
+	maxDepolarizedWires = 2
+	// widest classical register a ?c== condition compares as one number
+	maxRegisterBits = 63
+	// most classical registers compared circuits may average over, each holds its own ρ
+	maxComparedBranches = 1 << 8
+	// stabilizer results list every outcome when there are at most 2^this many
+	maxStabilizerOutcomes = 12
+	// widest stabilizer state whose generators are printed
+	maxListedStabilizers = 64
+	// widest mps bond when the circuit doesn't say
+	defaultBondDimension = 64
+	// mps results list every amplitude up to this many wires
+	maxListedMPSWires = 12
+	// wider mps results list the amplitudes of the bitstrings this many samples turn up
+	listedMPSSamples = 64
+	// max wire index for unitaries, which are the square of the state vector (2^10 x 2^10 is ~16MB)
+	maxUnitaryWires = 9
+	// widest reduced density matrix, they're dense 2^k x 2^k and their entropy needs their eigenvalues
+	maxReducedWires = 8
+	// widest circuit whose per wire entropies the entanglement panel lists
+	maxListedEntropyWires = 64
 	// max gates
 	maxGates = 99_999
+	// trajectories averaged by the trajectory backend when the circuit doesn't say
+	defaultTrajectories = 1000
 
 	//! errors
 
-	ErrUnknownGate         = errors.New("unknown gate")
-	ErrDuplicateWire	   = errors.New("duplicate wire")
-	ErrInvalidWireFormat   = errors.New("invalid wire format")
-	ErrInvalidArgument     = errors.New("invalid argument")
-	ErrGateMatrixNotSquare = errors.New("gate matrix is not square")
-	ErrInvalidWireCount    = errors.New("invalid wire count")
-	ErrInvalidBarrier      = errors.New("invalid barrier")
-	ErrTooManyGates        = errors.New(fmt.Sprintf("too many gates, max: %d", maxGates))
-	ErrTooManyWires        = errors.New(fmt.Sprintf("too many wires, max: %d", maxWires))
+	ErrUnknownGate             = errors.New("unknown gate")
+	ErrDuplicateWire           = errors.New("duplicate wire")
+	ErrInvalidWireFormat       = errors.New("invalid wire format")
+	ErrInvalidArgument         = errors.New("invalid argument")
+	ErrGateMatrixNotSquare     = errors.New("gate matrix is not square")
+	ErrInvalidWireCount        = errors.New("invalid wire count")
+	ErrInvalidBarrier          = errors.New("invalid barrier")
+	ErrTooManyGates            = errors.New(fmt.Sprintf("too many gates, max: %d", maxGates))
+	ErrTooManyWires            = errors.New("too many wires")
+	ErrTooManyCbits            = errors.New("classical bit index too high")
+	ErrInvalidShots            = errors.New("shots must be at least 1")
+	ErrNotMeasurement          = errors.New("gate is not a measurement")
+	ErrImpossibleOutcome       = errors.New("measurement outcome has zero probability")
+	ErrInvalidCondition        = errors.New("invalid classical condition")
+	ErrUnknownBackend          = errors.New("unknown backend")
+	ErrInvalidKraus            = errors.New("kraus operators must be square, act on whole wires and satisfy sum K†K = I")
+	ErrNoiseUnsupported        = errors.New("noise channels need the density or trajectory backend")
+	ErrInvalidNoiseModel       = errors.New("invalid noise model")
+	ErrInvalidTrajectories     = errors.New("trajectories must be at least 2")
+	ErrNotClifford             = errors.New("the stabilizer backend only runs clifford gates")
+	ErrInvalidBondDimension    = errors.New("bond dimension must be at least 1")
+	ErrInvalidBitstring        = errors.New("invalid bitstring")
+	ErrBackendExists           = errors.New("backend already registered")
+	ErrGateExists              = errors.New("gate already registered")
+	ErrInvalidInitialState     = errors.New("invalid initial state")
+	ErrProductStateOnly        = errors.New("the stabilizer backend only starts from product states")
+	ErrNotUnitary              = errors.New("operation is not unitary")
+	ErrInvalidObservable       = errors.New("invalid observable")
+	ErrExpectationUnsupported  = errors.New("the result doesn't hold enough of the state for this expectation")
+	ErrReducedStateUnsupported = errors.New("the result doesn't hold enough of the state for a reduced density matrix")
+	ErrComparisonUnsupported   = errors.New("the results don't hold enough of their states to compare")
 )
+
+// sets the max wire index the state vector backends may use
+func SetMaxWires(n int) error {
+	if n < 0 {
+		return ErrInvalidArgument
+	}
+	maxWires = n
+	return nil
+}
+
+// current max wire index the state vector backends may use
+func MaxWires() int {
+	return maxWires
+}
+
+// highest wire index a circuit string may use, wider circuits than MaxWires can
+// still run on the stabilizer and mps backends
+func maxParsedWire() int {
+	return max(maxWires, maxStabilizerWires)
+}
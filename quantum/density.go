@@ -0,0 +1,240 @@
+package quantum
+
+import (
+	"fmt"
+	"math/cmplx"
+	"strings"
+)
+
+// density matrix simulation
+//
+// ρ is stored as a dense 2^n x 2^n Matrix using the same wire/bit layout as the
+// state vector, so it costs the square of the state vector and the qubit cap is
+// halved. measurements stay selective (they follow the same outcomes as the state
+// vector path) so classical conditions keep working, while resets and channels
+// act on ρ exactly.
+
+// runs the circuit up to barrier n on a density matrix
+func (c *Circuit) executeDensity(atBarrier int) (Result, error) {
+	numQubits := c.NumQubits()
+	if maxDensity := (maxWires+1)/2 - 1; numQubits > maxDensity+1 {
+		return Result{}, fmt.Errorf("%w, max: %d", ErrTooManyWires, maxDensity)
+	}
+
+	state, classical, err := c.advance(BackendDensity, atBarrier, func() (checkpoint, error) {
+		return &densityCheckpoint{c.initialDensity(numQubits), numQubits}, nil
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	rho := state.(*densityCheckpoint).rho
+
+	return densityResult(rho, numQubits, classical), nil
+}
+
+// ρ = |ψ⟩⟨ψ| of the initial state
+func (c *Circuit) initialDensity(numQubits int) Matrix {
+	psi := c.Initial.vector(numQubits)
+	rho := NewMatrix(1<<numQubits, 1<<numQubits)
+	for i, a := range psi {
+		if a == 0 {
+			continue
+		}
+		for j, b := range psi {
+			rho.Data[i][j] = a * cmplx.Conj(b)
+		}
+	}
+	return rho
+}
+
+// runs gates from..to-1 of the circuit on ρ in place, measurement outcomes are written to classical
+func (c *Circuit) runDensityGates(rho *Matrix, numQubits int, classical []int, from, to int) error {
+	for i := from; i < to; i++ {
+		gate := c.Gates[i]
+		if !gate.Condition.Holds(classical) {
+			continue
+		}
+		switch g := gate.Gate.(type) {
+		case MeasureGate:
+			outcome, err := c.measureDensityWire(rho, numQubits, i, gate.Wires[0])
+			if err != nil {
+				return fmt.Errorf("gate %d: %w", i+1, err)
+			}
+			classical[gate.Cbits[0]] = c.RecordedOutcome(i, gate.Wires[0], outcome)
+		case ResetGate:
+			applyKraus(rho, numQubits, resetKraus(), gate.Wires)
+		case ChannelInterface:
+			applyKraus(rho, numQubits, g.Kraus(), gate.Wires)
+		case ControlledGate:
+			data := g.inner.Data()
+			if data.Rows != 1<<(len(gate.Wires)-len(g.state)) {
+				return ErrInvalidWireCount
+			}
+			conjugateByControlled(rho, numQubits, data, gate.Wires[:len(g.state)], g.state, gate.Wires[len(g.state):])
+			for _, op := range c.Noise.after(gate) {
+				applyKraus(rho, numQubits, op.channel.Kraus(), op.wires)
+			}
+		default:
+			data := gate.Gate.Data()
+			if data.Rows != data.Cols {
+				return ErrGateMatrixNotSquare
+			}
+			if data.Rows != 1<<len(gate.Wires) {
+				return ErrInvalidWireCount
+			}
+			conjugateBy(rho, numQubits, data, gate.Wires)
+			for _, op := range c.Noise.after(gate) {
+				applyKraus(rho, numQubits, op.channel.Kraus(), op.wires)
+			}
+		}
+	}
+	return nil
+}
+
+// ρ -> K ρ K† in place for an operator K on the given wires, ρ must be Hermitian
+func conjugateBy(rho *Matrix, numQubits int, k Matrix, wires []int) {
+	kConj := NewMatrix(k.Rows, k.Cols)
+	for i := range k.Data {
+		for j := range k.Data[i] {
+			kConj.Data[i][j] = cmplx.Conj(k.Data[i][j])
+		}
+	}
+
+	// rows of ρ times conj(K) give ρK†, its dagger is Kρ, and once more gives KρK†
+	for _, row := range rho.Data {
+		applyGateKernel(row, numQubits, kConj, wires)
+	}
+	daggerInPlace(rho)
+	for _, row := range rho.Data {
+		applyGateKernel(row, numQubits, kConj, wires)
+	}
+}
+
+// ρ -> KρK† for a controlled gate, which conjugates to the controlled conj(inner)
+func conjugateByControlled(rho *Matrix, numQubits int, inner Matrix, controls []int, state string, targets []int) {
+	innerConj := NewMatrix(inner.Rows, inner.Cols)
+	for i := range inner.Data {
+		for j := range inner.Data[i] {
+			innerConj.Data[i][j] = cmplx.Conj(inner.Data[i][j])
+		}
+	}
+	for _, row := range rho.Data {
+		applyControlledKernel(row, numQubits, innerConj, controls, state, targets)
+	}
+	daggerInPlace(rho)
+	for _, row := range rho.Data {
+		applyControlledKernel(row, numQubits, innerConj, controls, state, targets)
+	}
+}
+
+// ρ -> Σ K ρ K† for a set of Kraus operators on the given wires
+func applyKraus(rho *Matrix, numQubits int, kraus []Matrix, wires []int) {
+	sum := NewMatrix(rho.Rows, rho.Cols)
+	for _, k := range kraus {
+		term := copyMatrix(*rho)
+		conjugateBy(&term, numQubits, k, wires)
+		for i := range sum.Data {
+			for j := range sum.Data[i] {
+				sum.Data[i][j] += term.Data[i][j]
+			}
+		}
+	}
+	*rho = sum
+}
+
+// Kraus operators that trace a wire out and reprepare it in |0⟩
+func resetKraus() []Matrix {
+	return []Matrix{
+		{Rows: 2, Cols: 2, Data: [][]complex128{{1, 0}, {0, 0}}},
+		{Rows: 2, Cols: 2, Data: [][]complex128{{0, 1}, {0, 0}}},
+	}
+}
+
+// measures wire with the Born rule, projecting ρ onto the outcome
+func (c *Circuit) measureDensityWire(rho *Matrix, numQubits, index, wire int) (int, error) {
+	p1 := densityProbabilityOfOne(*rho, numQubits, wire)
+	outcome, err := c.MeasurementOutcome(index, p1)
+	if err != nil {
+		return 0, err
+	}
+	p := p1
+	if outcome == 0 {
+		p = 1 - p1
+	}
+	projectDensityWire(rho, numQubits, wire, outcome, p)
+	return outcome, nil
+}
+
+// chance that wire reads 1 in ρ
+func densityProbabilityOfOne(rho Matrix, numQubits, wire int) float64 {
+	m := wireMask(wire, numQubits)
+	p1 := 0.0
+	for i := range rho.Data {
+		if i&m != 0 {
+			p1 += real(rho.Data[i][i])
+		}
+	}
+	return p1
+}
+
+// projects ρ onto wire reading outcome and renormalises, p is the probability of that outcome
+func projectDensityWire(rho *Matrix, numQubits, wire, outcome int, p float64) {
+	m := wireMask(wire, numQubits)
+	scale := complex(1/p, 0)
+	for i := range rho.Data {
+		for j := range rho.Data[i] {
+			if (i&m != 0) == (outcome == 1) && (j&m != 0) == (outcome == 1) {
+				rho.Data[i][j] *= scale
+			} else {
+				rho.Data[i][j] = 0
+			}
+		}
+	}
+}
+
+// Tr(ρ²), 1 for pure states down to 1/2^n for the maximally mixed state
+func Purity(rho Matrix) float64 {
+	purity := 0.0
+	for i := range rho.Data {
+		for j := range rho.Data[i] {
+			v := rho.Data[i][j]
+			purity += real(v)*real(v) + imag(v)*imag(v)
+		}
+	}
+	return purity
+}
+
+// builds a result from ρ, probabilities are its diagonal
+func densityResult(rho Matrix, numQubits int, classical []int) Result {
+	probabilities := make(map[string]float64)
+	for i := range rho.Data {
+		if p := real(rho.Data[i][i]); p > 1e-15 {
+			key := strings.Join(intToBitString(i, numQubits), "")
+			probabilities[key] = p
+		}
+	}
+
+	return Result{
+		Probabilities:     probabilities,
+		ClassicalRegister: classical,
+		DensityMatrix:     &rho,
+		Purity:            Purity(rho),
+	}
+}
+
+func daggerInPlace(m *Matrix) {
+	for i := 0; i < m.Rows; i++ {
+		m.Data[i][i] = cmplx.Conj(m.Data[i][i])
+		for j := i + 1; j < m.Cols; j++ {
+			m.Data[i][j], m.Data[j][i] = cmplx.Conj(m.Data[j][i]), cmplx.Conj(m.Data[i][j])
+		}
+	}
+}
+
+func copyMatrix(m Matrix) Matrix {
+	out := NewMatrix(m.Rows, m.Cols)
+	for i := range m.Data {
+		copy(out.Data[i], m.Data[i])
+	}
+	return out
+}
@@ -0,0 +1,69 @@
+package quantum
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDensityMatchesStateVector(t *testing.T) {
+	gates := "h0 cnot0,2 rx1(pi/3) toff2,0,1 crz1,2(pi/5) swap0,2 ry0(0.4) cz2,0 t1"
+	circuit, err := NewCircuit(strings.Split(gates, " "))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for barrier := 1; barrier <= len(circuit.Gates); barrier++ {
+		circuit.Backend = BackendStateVector
+		pure, err := circuit.ExecuteToBarrier(barrier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		circuit.Backend = BackendDensity
+		mixed, err := circuit.ExecuteToBarrier(barrier)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if math.Abs(mixed.Purity-1) > 1e-9 {
+			t.Errorf("barrier %d: unitary circuit should stay pure, purity %v", barrier, mixed.Purity)
+		}
+		for key, p := range pure.Probabilities {
+			if math.Abs(mixed.Probabilities[key]-p) > 1e-9 {
+				t.Errorf("barrier %d: P(%s) = %v, want %v", barrier, key, mixed.Probabilities[key], p)
+			}
+		}
+
+		// ρ should be |ψ⟩⟨ψ|
+		rho := mixed.DensityMatrix
+		for i := range rho.Data {
+			for j := range rho.Data[i] {
+				bi := strings.Join(intToBitString(i, 3), "")
+				bj := strings.Join(intToBitString(j, 3), "")
+				want := pure.StateVector[bi] * complex(real(pure.StateVector[bj]), -imag(pure.StateVector[bj]))
+				if d := rho.Data[i][j] - want; math.Hypot(real(d), imag(d)) > 1e-9 {
+					t.Fatalf("barrier %d: ρ[%d][%d] = %v, want %v", barrier, i, j, rho.Data[i][j], want)
+				}
+			}
+		}
+	}
+}
+
+func TestDensityResetMixesEntangledPartner(t *testing.T) {
+	circuit, err := NewCircuit(strings.Split("h0 cnot0,1 reset1", " "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	circuit.Backend = BackendDensity
+
+	result, err := circuit.ExecuteToBarrier(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(result.Purity-0.5) > 1e-9 {
+		t.Errorf("purity = %v, want 0.5", result.Purity)
+	}
+	if math.Abs(result.Probabilities["00"]-0.5) > 1e-9 || math.Abs(result.Probabilities["10"]-0.5) > 1e-9 {
+		t.Errorf("unexpected probabilities %v", result.Probabilities)
+	}
+}
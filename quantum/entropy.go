@@ -0,0 +1,230 @@
+package quantum
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sort"
+	"strings"
+)
+
+// reduced states and entanglement
+//
+// a subset of wires is described by its reduced density matrix, what's left of ρ once
+// every other wire is traced out. its von Neumann entropy is 0 for a wire that isn't
+// entangled with the rest and grows by a bit per maximally entangled pair that is cut
+
+// traces the listed wires out of ρ, the kept wires stay in order with the first as the top bit
+func PartialTrace(rho Matrix, traced []int) (Matrix, error) {
+	numQubits := 0
+	for 1<<numQubits < rho.Rows {
+		numQubits++
+	}
+	if rho.Rows != 1<<numQubits || rho.Cols != rho.Rows {
+		return Matrix{}, ErrInvalidWireCount
+	}
+	keep, err := complementWires(traced, numQubits)
+	if err != nil {
+		return Matrix{}, err
+	}
+
+	dim := 1 << len(keep)
+	reduced := NewMatrix(dim, dim)
+	for b := 0; b < 1<<len(traced); b++ {
+		tracedBits := spreadBits(b, traced, numQubits)
+		for a := 0; a < dim; a++ {
+			i := spreadBits(a, keep, numQubits) | tracedBits
+			for a2 := 0; a2 < dim; a2++ {
+				reduced.Data[a][a2] += rho.Data[i][spreadBits(a2, keep, numQubits)|tracedBits]
+			}
+		}
+	}
+	return reduced, nil
+}
+
+// von Neumann entropy -Tr ρ log2 ρ in bits
+func VonNeumannEntropy(rho Matrix) float64 {
+	entropy := 0.0
+	for _, p := range psdEigenvalues(rho.Data) {
+		if p > 1e-12 {
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return math.Max(0, entropy)
+}
+
+// places the bits of value on wires of a numQubits index, the first wire taking the top bit
+func spreadBits(value int, wires []int, numQubits int) int {
+	index := 0
+	for j, wire := range wires {
+		if value>>(len(wires)-1-j)&1 == 1 {
+			index |= 1 << (numQubits - 1 - wire)
+		}
+	}
+	return index
+}
+
+// the wires of numQubits not listed, ascending, after checking the list
+func complementWires(wires []int, numQubits int) ([]int, error) {
+	listed := make(map[int]bool)
+	for _, wire := range wires {
+		if wire < 0 || wire >= numQubits {
+			return nil, fmt.Errorf("%w: wire %d of %d", ErrInvalidWireCount, wire, numQubits)
+		}
+		if listed[wire] {
+			return nil, ErrDuplicateWire
+		}
+		listed[wire] = true
+	}
+	var rest []int
+	for wire := 0; wire < numQubits; wire++ {
+		if !listed[wire] {
+			rest = append(rest, wire)
+		}
+	}
+	return rest, nil
+}
+
+// how many wires the result's state spans, 0 when it can't tell
+func (r Result) numQubits() int {
+	if r.DensityMatrix != nil {
+		numQubits := 0
+		for 1<<numQubits < r.DensityMatrix.Rows {
+			numQubits++
+		}
+		return numQubits
+	}
+	if r.Amplitudes != nil {
+		return amplitudeVector(r.Amplitudes).numQubits()
+	}
+	if t, ok := r.expecter.(*tableau); ok {
+		return t.n
+	}
+	if m, ok := r.expecter.(*mps); ok {
+		return len(m.sites)
+	}
+	for key := range r.StateVector {
+		return len(key)
+	}
+	for key := range r.Probabilities {
+		return len(key)
+	}
+	return 0
+}
+
+// reduced density matrix of the kept wires, in ascending order with the first as the top bit.
+// it needs the density matrix, every amplitude or a backend that answers Pauli expectations
+func (r Result) ReducedDensityMatrix(keep []int) (Matrix, error) {
+	numQubits := r.numQubits()
+	traced, err := complementWires(keep, numQubits)
+	if err != nil {
+		return Matrix{}, err
+	}
+	keep = append([]int(nil), keep...)
+	sort.Ints(keep)
+	if len(keep) > maxReducedWires {
+		return Matrix{}, fmt.Errorf("%w, max: %d kept wires", ErrTooManyWires, maxReducedWires)
+	}
+
+	switch {
+	case r.DensityMatrix != nil:
+		return PartialTrace(*r.DensityMatrix, traced)
+	case r.Amplitudes != nil:
+		dim := 1 << len(keep)
+		return Matrix{Rows: dim, Cols: dim, Data: wireDensity(r.Amplitudes, numQubits, keep)}, nil
+	case complete(r.StateVector):
+		return reduceAmplitudes(r.StateVector, keep), nil
+	case r.expecter != nil:
+		return r.tomography(keep), nil
+	}
+	return Matrix{}, ErrReducedStateUnsupported
+}
+
+// ρ_A[a][a'] = Σ_b ψ[a b] conj(ψ[a' b]), summed within each group of keys sharing b
+func reduceAmplitudes(amplitudes map[string]complex128, keep []int) Matrix {
+	type entry struct {
+		index     int
+		amplitude complex128
+	}
+	kept := make(map[int]bool)
+	for _, wire := range keep {
+		kept[wire] = true
+	}
+	groups := make(map[string][]entry)
+	for key, amplitude := range amplitudes {
+		index := 0
+		var rest strings.Builder
+		for wire := range key {
+			if kept[wire] {
+				index = index<<1 | int(key[wire]-'0')
+			} else {
+				rest.WriteByte(key[wire])
+			}
+		}
+		groups[rest.String()] = append(groups[rest.String()], entry{index, amplitude})
+	}
+
+	reduced := NewMatrix(1<<len(keep), 1<<len(keep))
+	for _, group := range groups {
+		for _, a := range group {
+			for _, b := range group {
+				reduced.Data[a.index][b.index] += a.amplitude * cmplx.Conj(b.amplitude)
+			}
+		}
+	}
+	return reduced
+}
+
+// ρ_A = 2^-k Σ ⟨P⟩ P over the 4^k Pauli strings on the kept wires
+func (r Result) tomography(keep []int) Matrix {
+	k := len(keep)
+	dim := 1 << k
+	reduced := NewMatrix(dim, dim)
+	for code := 0; code < 1<<(2*k); code++ {
+		term := PauliTerm{Coefficient: 1, Paulis: make(map[int]byte)}
+		local := PauliTerm{Coefficient: 1, Paulis: make(map[int]byte)}
+		for j, wire := range keep {
+			if pauli := "IXYZ"[code>>(2*j)&3]; pauli != 'I' {
+				term.Paulis[wire] = pauli
+				local.Paulis[j] = pauli
+			}
+		}
+		value := r.expecter.expectation(term)
+		if value == 0 {
+			continue
+		}
+		xMask, zMask, ys, _ := pauliMasks(local, k)
+		for i := 0; i < dim; i++ {
+			reduced.Data[i^xMask][i] += complex(value/float64(dim), 0) * pauliPhase(i, zMask, ys)
+		}
+	}
+	return reduced
+}
+
+// entanglement entropy in bits between the listed wires and the rest of the circuit
+func (r Result) Entropy(wires []int) (float64, error) {
+	numQubits := r.numQubits()
+	rest, err := complementWires(wires, numQubits)
+	if err != nil {
+		return 0, err
+	}
+	if t, ok := r.expecter.(*tableau); ok {
+		return t.entropy(wires), nil
+	}
+	m, chain := r.expecter.(*mps)
+	if chain {
+		if cut, ok := m.cutOf(wires); ok {
+			return m.cutEntropy(cut), nil
+		}
+	}
+
+	// a pure state has the same entropy on both sides of the cut, so the smaller one does
+	if r.DensityMatrix == nil && (chain || r.Amplitudes != nil || complete(r.StateVector)) && len(rest) < len(wires) {
+		wires = rest
+	}
+	reduced, err := r.ReducedDensityMatrix(wires)
+	if err != nil {
+		return 0, err
+	}
+	return VonNeumannEntropy(reduced), nil
+}
@@ -0,0 +1,261 @@
+package quantum
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func runCircuit(t *testing.T, gates []string, backend string) Result {
+	t.Helper()
+	c, err := NewCircuit(gates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Backend = backend
+	result, err := c.ExecuteToBarrier(len(gates))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// a random circuit of n gates, each a single on one wire or, about half the time, one of
+// the doubles on as many distinct wires as it takes. gates that need arguments get angles
+// in [0, π)
+func randomCircuit(rng *rand.Rand, numQubits, n int, singles, doubles []string) []string {
+	var gates []string
+	for len(gates) < n {
+		name := singles[rng.Intn(len(singles))]
+		if rng.Intn(2) == 0 {
+			name = doubles[rng.Intn(len(doubles))]
+		}
+		spec, _ := LookupGate(name)
+		if max(spec.Wires, 1) > numQubits {
+			continue
+		}
+		var wires []string
+		for _, wire := range rng.Perm(numQubits)[:max(spec.Wires, 1)] {
+			wires = append(wires, fmt.Sprint(wire))
+		}
+		gate := name + strings.Join(wires, ",")
+		if spec.Params > 0 && spec.Defaults == nil {
+			var angles []string
+			for i := 0; i < spec.Params; i++ {
+				angles = append(angles, fmt.Sprintf("%.3f", rng.Float64()*math.Pi))
+			}
+			gate += "(" + strings.Join(angles, ",") + ")"
+		}
+		gates = append(gates, gate)
+	}
+	return gates
+}
+
+func TestPartialTrace(t *testing.T) {
+	// |01⟩⟨01| keeps |1⟩⟨1| on wire 1 and |0⟩⟨0| on wire 0
+	rho := NewMatrix(4, 4)
+	rho.Data[1][1] = 1
+	for traced, want := range map[int][]complex128{0: {0, 0, 0, 1}, 1: {1, 0, 0, 0}} {
+		reduced, err := PartialTrace(rho, []int{traced})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []complex128{reduced.Data[0][0], reduced.Data[0][1], reduced.Data[1][0], reduced.Data[1][1]}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("tracing wire %d gave %v, want %v", traced, got, want)
+			}
+		}
+	}
+
+	if _, err := PartialTrace(rho, []int{0, 0}); !errors.Is(err, ErrDuplicateWire) {
+		t.Errorf("expected ErrDuplicateWire, got %v", err)
+	}
+	if _, err := PartialTrace(rho, []int{2}); !errors.Is(err, ErrInvalidWireCount) {
+		t.Errorf("expected ErrInvalidWireCount, got %v", err)
+	}
+}
+
+func TestGHZEntropy(t *testing.T) {
+	gates := []string{"h0", "cnot0,1", "cnot1,2", "cnot2,3"}
+	for _, backend := range []string{BackendStateVector, BackendDensity, BackendStabilizer, BackendMPS} {
+		result := runCircuit(t, gates, backend)
+		for _, wires := range [][]int{{0}, {3}, {0, 2}, {1, 2, 3}} {
+			entropy, err := result.Entropy(wires)
+			if err != nil {
+				t.Fatalf("%s: %v", backend, err)
+			}
+			if math.Abs(entropy-1) > 1e-9 {
+				t.Errorf("%s: S(%v) = %v, want 1", backend, wires, entropy)
+			}
+		}
+		reduced, err := result.ReducedDensityMatrix([]int{2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(Purity(reduced)-0.5) > 1e-9 {
+			t.Errorf("%s: purity of wire 2 = %v, want 0.5", backend, Purity(reduced))
+		}
+	}
+}
+
+func TestReducedStatesMatchAcrossBackends(t *testing.T) {
+	rng := rand.New(rand.NewSource(8))
+	single := []string{"h", "x", "t", "s", "rx", "ry"}
+	multi := []string{"cnot", "cz", "swap", "crx"}
+	for trial := 0; trial < 15; trial++ {
+		numQubits := 3 + rng.Intn(3)
+		gates := randomCircuit(rng, numQubits, 20, single, multi)
+		keep := rng.Perm(numQubits)[:1+rng.Intn(numQubits-1)]
+
+		want := runCircuit(t, gates, BackendStateVector)
+		wantReduced, err := want.ReducedDensityMatrix(keep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantEntropy, err := want.Entropy(keep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rest, _ := complementWires(keep, numQubits)
+		if restEntropy, _ := want.Entropy(rest); math.Abs(restEntropy-wantEntropy) > 1e-8 {
+			t.Errorf("pure state: S(%v) = %v but S(%v) = %v", keep, wantEntropy, rest, restEntropy)
+		}
+
+		for _, backend := range []string{BackendDensity, BackendMPS} {
+			got := runCircuit(t, gates, backend)
+			reduced, err := got.ReducedDensityMatrix(keep)
+			if err != nil {
+				t.Fatalf("%s: %v", backend, err)
+			}
+			for i := range reduced.Data {
+				for j := range reduced.Data[i] {
+					if cmplx.Abs(reduced.Data[i][j]-wantReduced.Data[i][j]) > 1e-9 {
+						t.Fatalf("%s: reduced state of %v differs at %d,%d", backend, keep, i, j)
+					}
+				}
+			}
+			entropy, err := got.Entropy(keep)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(entropy-wantEntropy) > 1e-8 {
+				t.Errorf("%s: S(%v) = %v, want %v", backend, keep, entropy, wantEntropy)
+			}
+		}
+	}
+}
+
+func TestStabilizerEntropyMatchesStateVector(t *testing.T) {
+	rng := rand.New(rand.NewSource(9))
+	single := []string{"h", "s", "x", "z"}
+	double := []string{"cnot", "cz", "swap"}
+	for trial := 0; trial < 30; trial++ {
+		numQubits := 2 + rng.Intn(4)
+		gates := randomCircuit(rng, numQubits, 25, single, double)
+		keep := rng.Perm(numQubits)[:1+rng.Intn(numQubits)]
+
+		want, err := runCircuit(t, gates, BackendStateVector).Entropy(keep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stabilizer := runCircuit(t, gates, BackendStabilizer)
+		got, err := stabilizer.Entropy(keep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(got-want) > 1e-8 {
+			t.Errorf("%v: S(%v) = %v, state vector has %v", gates, keep, got, want)
+		}
+	}
+
+	// wide enough that only the tableau can answer
+	gates := []string{"h0"}
+	for i := 1; i < 500; i++ {
+		gates = append(gates, fmt.Sprintf("cnot%d,%d", i-1, i))
+	}
+	half := make([]int, 250)
+	for i := range half {
+		half[i] = i
+	}
+	entropy, err := runCircuit(t, gates, BackendStabilizer).Entropy(half)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entropy != 1 {
+		t.Errorf("500 wire GHZ cut in half has entropy %v, want 1", entropy)
+	}
+}
+
+func TestMPSWideReducedStates(t *testing.T) {
+	// bell pairs on neighbouring wires, then a flipped and a rotated wire at the end
+	var gates []string
+	for i := 0; i < 58; i += 2 {
+		gates = append(gates, fmt.Sprintf("h%d", i), fmt.Sprintf("cnot%d,%d", i, i+1))
+	}
+	gates = append(gates, "x58", "ry59(0.5)")
+	result := runCircuit(t, gates, BackendMPS)
+
+	span := func(from, to int) []int {
+		var wires []int
+		for i := from; i < to; i++ {
+			wires = append(wires, i)
+		}
+		return wires
+	}
+	tests := []struct {
+		wires []int
+		want  float64
+	}{
+		{[]int{0}, 1},
+		{[]int{5}, 1},
+		{[]int{3, 4}, 2},
+		{[]int{4, 5}, 0},
+		{[]int{58}, 0},
+		{span(0, 30), 0},
+		{span(0, 31), 1},
+		{span(31, 60), 1},
+		{span(2, 60), 0},
+	}
+	for _, tt := range tests {
+		entropy, err := result.Entropy(tt.wires)
+		if err != nil {
+			t.Fatalf("S(%v): %v", tt.wires, err)
+		}
+		if math.Abs(entropy-tt.want) > 1e-8 {
+			t.Errorf("S(%v) = %v, want %v", tt.wires, entropy, tt.want)
+		}
+	}
+
+	for wire, want := range map[int]BlochVector{
+		0:  {},
+		31: {},
+		58: {Z: -1},
+		59: {X: math.Sin(0.5), Z: math.Cos(0.5)},
+	} {
+		got, err := result.BlochVector(wire)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(got.X-want.X) > 1e-9 || math.Abs(got.Y-want.Y) > 1e-9 || math.Abs(got.Z-want.Z) > 1e-9 {
+			t.Errorf("Bloch vector of wire %d = %v, want %v", wire, got, want)
+		}
+	}
+}
+
+func TestReducedStateUnsupported(t *testing.T) {
+	c, _ := NewCircuit([]string{"h0", "cnot0,1"})
+	c.Backend = BackendTrajectory
+	c.Trajectories = 2
+	result, err := c.ExecuteToBarrier(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := result.ReducedDensityMatrix([]int{0}); !errors.Is(err, ErrReducedStateUnsupported) {
+		t.Errorf("expected ErrReducedStateUnsupported, got %v", err)
+	}
+}
@@ -3,6 +3,9 @@ package quantum
 import (
 	"fmt"
 	"math"
+	"math/cmplx"
+	"strconv"
+	"strings"
 )
 
 func (g Gate) Name() string {
@@ -430,6 +433,7 @@ func S() GateInterface {
 
 type PhaseGate struct {
 	Gate
+	lambda float64
 }
 
 func (g PhaseGate) WiresNeeded() int {
@@ -437,17 +441,29 @@ func (g PhaseGate) WiresNeeded() int {
 }
 
 func (g PhaseGate) Example() string {
-	return "p0"
+	return "p0(pi/4)"
 }
 
 func (g PhaseGate) FullName() string {
 	return "Phase"
 }
 
+func (g PhaseGate) Name() string {
+	return fmt.Sprintf("P(%.2f)", g.lambda)
+}
+
+// P(π/2), the phase gate before it took an angle.
+//
+// Deprecated: use PhaseShift, or S for the same gate.
 func Phase() GateInterface {
+	return PhaseShift(math.Pi / 2)
+}
+
+// e^(iλ) on |1⟩, P(π/2) is S and P(π/4) is T
+func PhaseShift(lambda float64) GateInterface {
 	matrix := [][]complex128{
 		{1, 0},
-		{0, 1i},
+		{0, cmplx.Exp(complex(0, lambda))},
 	}
 	return PhaseGate{
 		Gate: Gate{
@@ -458,6 +474,7 @@ func Phase() GateInterface {
 			},
 			name: "P",
 		},
+		lambda: lambda,
 	}
 }
 
@@ -701,6 +718,97 @@ func CRz(theta float64) GateInterface {
 	}
 }
 
+type UGate struct {
+	Gate
+	theta, phi, lambda float64
+}
+
+func (g UGate) WiresNeeded() int {
+	return 1
+}
+
+func (g UGate) Example() string {
+	return "u0(pi/2,0,pi)"
+}
+
+func (g UGate) FullName() string {
+	return "Universal"
+}
+
+func (g UGate) Name() string {
+	return fmt.Sprintf("U(%.2f,%.2f,%.2f)", g.theta, g.phi, g.lambda)
+}
+
+// any single wire unitary up to global phase, U(θ,φ,λ) = Rz(φ) Ry(θ) Rz(λ) e^{i(φ+λ)/2}
+func U(theta, phi, lambda float64) GateInterface {
+	return UGate{
+		Gate: Gate{
+			Matrix: Matrix{
+				Rows: 2,
+				Cols: 2,
+				Data: uMatrix(theta, phi, lambda),
+			},
+			name: "U",
+		},
+		theta:  theta,
+		phi:    phi,
+		lambda: lambda,
+	}
+}
+
+func uMatrix(theta, phi, lambda float64) [][]complex128 {
+	cos := complex(math.Cos(theta/2), 0)
+	sin := complex(math.Sin(theta/2), 0)
+	return [][]complex128{
+		{cos, -cmplx.Exp(complex(0, lambda)) * sin},
+		{cmplx.Exp(complex(0, phi)) * sin, cmplx.Exp(complex(0, phi+lambda)) * cos},
+	}
+}
+
+type CUGate struct {
+	Gate
+	theta, phi, lambda float64
+}
+
+func (g CUGate) WiresNeeded() int {
+	return 2
+}
+
+func (g CUGate) Example() string {
+	return "cu0,1(pi/2,0,pi)"
+}
+
+func (g CUGate) FullName() string {
+	return "Controlled-Universal"
+}
+
+func (g CUGate) Name() string {
+	return fmt.Sprintf("CU(%.2f,%.2f,%.2f)", g.theta, g.phi, g.lambda)
+}
+
+func CU(theta, phi, lambda float64) GateInterface {
+	u := uMatrix(theta, phi, lambda)
+	matrix := [][]complex128{
+		{1, 0, 0, 0},
+		{0, 1, 0, 0},
+		{0, 0, u[0][0], u[0][1]},
+		{0, 0, u[1][0], u[1][1]},
+	}
+	return CUGate{
+		Gate: Gate{
+			Matrix: Matrix{
+				Rows: 4,
+				Cols: 4,
+				Data: matrix,
+			},
+			name: "CU",
+		},
+		theta:  theta,
+		phi:    phi,
+		lambda: lambda,
+	}
+}
+
 type ToffoliGate struct {
 	Gate
 }
@@ -739,3 +847,594 @@ func Toffoli() GateInterface {
 		},
 	}
 }
+
+type SXGate struct {
+	Gate
+}
+
+func (g SXGate) WiresNeeded() int {
+	return 1
+}
+
+func (g SXGate) Example() string {
+	return "sx0"
+}
+
+func (g SXGate) FullName() string {
+	return "Sqrt-X"
+}
+
+// √X, two of them make an X
+func SX() GateInterface {
+	matrix := [][]complex128{
+		{complex(0.5, 0.5), complex(0.5, -0.5)},
+		{complex(0.5, -0.5), complex(0.5, 0.5)},
+	}
+	return SXGate{
+		Gate: Gate{
+			Matrix: Matrix{
+				Rows: 2,
+				Cols: 2,
+				Data: matrix,
+			},
+			name: "SX",
+		},
+	}
+}
+
+type CYGate struct {
+	Gate
+}
+
+func (g CYGate) WiresNeeded() int {
+	return 2
+}
+
+func (g CYGate) Example() string {
+	return "cy0,1"
+}
+
+func (g CYGate) FullName() string {
+	return "Controlled-Y"
+}
+
+func CY() GateInterface {
+	matrix := [][]complex128{
+		{1, 0, 0, 0},
+		{0, 1, 0, 0},
+		{0, 0, 0, -1i},
+		{0, 0, 1i, 0},
+	}
+	return CYGate{
+		Gate: Gate{
+			Matrix: Matrix{
+				Rows: 4,
+				Cols: 4,
+				Data: matrix,
+			},
+			name: "CY",
+		},
+	}
+}
+
+type CHGate struct {
+	Gate
+}
+
+func (g CHGate) WiresNeeded() int {
+	return 2
+}
+
+func (g CHGate) Example() string {
+	return "ch0,1"
+}
+
+func (g CHGate) FullName() string {
+	return "Controlled-Hadamard"
+}
+
+func CH() GateInterface {
+	h := complex(1/math.Sqrt(2), 0)
+	matrix := [][]complex128{
+		{1, 0, 0, 0},
+		{0, 1, 0, 0},
+		{0, 0, h, h},
+		{0, 0, h, -h},
+	}
+	return CHGate{
+		Gate: Gate{
+			Matrix: Matrix{
+				Rows: 4,
+				Cols: 4,
+				Data: matrix,
+			},
+			name: "CH",
+		},
+	}
+}
+
+type CPhaseGate struct {
+	Gate
+	lambda float64
+}
+
+func (g CPhaseGate) WiresNeeded() int {
+	return 2
+}
+
+func (g CPhaseGate) Example() string {
+	return "cp0,1(pi/2)"
+}
+
+func (g CPhaseGate) FullName() string {
+	return "Controlled-Phase"
+}
+
+func (g CPhaseGate) Name() string {
+	return fmt.Sprintf("CP(%.2f)", g.lambda)
+}
+
+// e^(iλ) on |11⟩, CP(π) is CZ
+func CPhase(lambda float64) GateInterface {
+	matrix := [][]complex128{
+		{1, 0, 0, 0},
+		{0, 1, 0, 0},
+		{0, 0, 1, 0},
+		{0, 0, 0, cmplx.Exp(complex(0, lambda))},
+	}
+	return CPhaseGate{
+		Gate: Gate{
+			Matrix: Matrix{
+				Rows: 4,
+				Cols: 4,
+				Data: matrix,
+			},
+			name: "CP",
+		},
+		lambda: lambda,
+	}
+}
+
+// SWAP of the last two wires controlled by the first. it's a controlled gate so Draw
+// puts the name on both swapped wires
+func Fredkin() GateInterface {
+	return ControlledGate{inner: SWAP(), state: "1", example: "cswap0,1,2"}
+}
+
+type ISWAPGate struct {
+	Gate
+}
+
+func (g ISWAPGate) WiresNeeded() int {
+	return 2
+}
+
+func (g ISWAPGate) Example() string {
+	return "iswap0,1"
+}
+
+func (g ISWAPGate) FullName() string {
+	return "iSWAP"
+}
+
+// swaps the wires and multiplies |01⟩ and |10⟩ by i
+func ISWAP() GateInterface {
+	matrix := [][]complex128{
+		{1, 0, 0, 0},
+		{0, 0, 1i, 0},
+		{0, 1i, 0, 0},
+		{0, 0, 0, 1},
+	}
+	return ISWAPGate{
+		Gate: Gate{
+			Matrix: Matrix{
+				Rows: 4,
+				Cols: 4,
+				Data: matrix,
+			},
+			name: "iSWAP",
+		},
+	}
+}
+
+type ECRGate struct {
+	Gate
+}
+
+func (g ECRGate) WiresNeeded() int {
+	return 2
+}
+
+func (g ECRGate) Example() string {
+	return "ecr0,1"
+}
+
+func (g ECRGate) FullName() string {
+	return "Echoed-Cross-Resonance"
+}
+
+// (X⊗I - Y⊗X)/√2, the first wire is the one the cross resonance drives
+func ECR() GateInterface {
+	h := complex(1/math.Sqrt(2), 0)
+	matrix := [][]complex128{
+		{0, 0, h, 1i * h},
+		{0, 0, 1i * h, h},
+		{h, -1i * h, 0, 0},
+		{-1i * h, h, 0, 0},
+	}
+	return ECRGate{
+		Gate: Gate{
+			Matrix: Matrix{
+				Rows: 4,
+				Cols: 4,
+				Data: matrix,
+			},
+			name: "ECR",
+		},
+	}
+}
+
+type RXXGate struct {
+	Gate
+	theta float64
+}
+
+func (g RXXGate) WiresNeeded() int {
+	return 2
+}
+
+func (g RXXGate) Example() string {
+	return "rxx0,1(pi/2)"
+}
+
+func (g RXXGate) FullName() string {
+	return "Rotate-XX"
+}
+
+func (g RXXGate) Name() string {
+	return fmt.Sprintf("Rxx(%.2f)", g.theta)
+}
+
+// e^(-iθ/2 X⊗X), the Ising XX coupling
+func RXX(theta float64) GateInterface {
+	c := complex(math.Cos(theta/2), 0)
+	s := complex(0, -math.Sin(theta/2))
+	matrix := [][]complex128{
+		{c, 0, 0, s},
+		{0, c, s, 0},
+		{0, s, c, 0},
+		{s, 0, 0, c},
+	}
+	return RXXGate{
+		Gate: Gate{
+			Matrix: Matrix{
+				Rows: 4,
+				Cols: 4,
+				Data: matrix,
+			},
+			name: "Rxx",
+		},
+		theta: theta,
+	}
+}
+
+type RYYGate struct {
+	Gate
+	theta float64
+}
+
+func (g RYYGate) WiresNeeded() int {
+	return 2
+}
+
+func (g RYYGate) Example() string {
+	return "ryy0,1(pi/2)"
+}
+
+func (g RYYGate) FullName() string {
+	return "Rotate-YY"
+}
+
+func (g RYYGate) Name() string {
+	return fmt.Sprintf("Ryy(%.2f)", g.theta)
+}
+
+// e^(-iθ/2 Y⊗Y), the Ising YY coupling
+func RYY(theta float64) GateInterface {
+	c := complex(math.Cos(theta/2), 0)
+	s := complex(0, -math.Sin(theta/2))
+	matrix := [][]complex128{
+		{c, 0, 0, -s},
+		{0, c, s, 0},
+		{0, s, c, 0},
+		{-s, 0, 0, c},
+	}
+	return RYYGate{
+		Gate: Gate{
+			Matrix: Matrix{
+				Rows: 4,
+				Cols: 4,
+				Data: matrix,
+			},
+			name: "Ryy",
+		},
+		theta: theta,
+	}
+}
+
+type RZZGate struct {
+	Gate
+	theta float64
+}
+
+func (g RZZGate) WiresNeeded() int {
+	return 2
+}
+
+func (g RZZGate) Example() string {
+	return "rzz0,1(pi/2)"
+}
+
+func (g RZZGate) FullName() string {
+	return "Rotate-ZZ"
+}
+
+func (g RZZGate) Name() string {
+	return fmt.Sprintf("Rzz(%.2f)", g.theta)
+}
+
+// e^(-iθ/2 Z⊗Z), the Ising ZZ coupling
+func RZZ(theta float64) GateInterface {
+	even := cmplx.Exp(complex(0, -theta/2))
+	odd := cmplx.Exp(complex(0, theta/2))
+	matrix := [][]complex128{
+		{even, 0, 0, 0},
+		{0, odd, 0, 0},
+		{0, 0, odd, 0},
+		{0, 0, 0, even},
+	}
+	return RZZGate{
+		Gate: Gate{
+			Matrix: Matrix{
+				Rows: 4,
+				Cols: 4,
+				Data: matrix,
+			},
+			name: "Rzz",
+		},
+		theta: theta,
+	}
+}
+
+type GlobalPhaseGate struct {
+	Gate
+	theta float64
+}
+
+func (g GlobalPhaseGate) WiresNeeded() int {
+	return 1
+}
+
+func (g GlobalPhaseGate) Example() string {
+	return "gphase0(pi/4)"
+}
+
+func (g GlobalPhaseGate) FullName() string {
+	return "Global-Phase"
+}
+
+func (g GlobalPhaseGate) Name() string {
+	return fmt.Sprintf("GPh(%.2f)", g.theta)
+}
+
+// e^(iθ) on every amplitude. it changes no probability, but shows in the amplitudes and
+// turns into a relative phase once controlled
+func GlobalPhase(theta float64) GateInterface {
+	phase := cmplx.Exp(complex(0, theta))
+	matrix := [][]complex128{
+		{phase, 0},
+		{0, phase},
+	}
+	return GlobalPhaseGate{
+		Gate: Gate{
+			Matrix: Matrix{
+				Rows: 2,
+				Cols: 2,
+				Data: matrix,
+			},
+			name: "GPh",
+		},
+		theta: theta,
+	}
+}
+
+type MeasureGate struct {
+	Gate
+}
+
+func (g MeasureGate) WiresNeeded() int {
+	return 1
+}
+
+func (g MeasureGate) Example() string {
+	return "m0->0"
+}
+
+func (g MeasureGate) FullName() string {
+	return "Measure"
+}
+
+// measurement is not unitary, Data is the identity and the executor collapses the wire itself
+func Measure() GateInterface {
+	return MeasureGate{
+		Gate: Gate{
+			Matrix: Identity(2).Data(),
+			name:   "(/)",
+		},
+	}
+}
+
+type ResetGate struct {
+	Gate
+}
+
+func (g ResetGate) WiresNeeded() int {
+	return 1
+}
+
+func (g ResetGate) Example() string {
+	return "reset0"
+}
+
+func (g ResetGate) FullName() string {
+	return "Reset"
+}
+
+// reset is not unitary, Data is the identity and the executor reprepares the wire itself
+func Reset() GateInterface {
+	return ResetGate{
+		Gate: Gate{
+			Matrix: Identity(2).Data(),
+			name:   "RESET",
+		},
+	}
+}
+
+type DaggerGate struct {
+	Gate
+	inner   GateInterface
+	example string
+}
+
+func (g DaggerGate) WiresNeeded() int {
+	return g.inner.WiresNeeded()
+}
+
+func (g DaggerGate) Example() string {
+	return g.example
+}
+
+func (g DaggerGate) FullName() string {
+	return g.inner.FullName() + "†"
+}
+
+// the gate that undoes g, its conjugate transpose. written inv(g) or g' in circuit strings
+func Dagger(g GateInterface) (GateInterface, error) {
+	switch g := g.(type) {
+	case MeasureGate, ResetGate, ChannelInterface:
+		return nil, fmt.Errorf("%w: %s has no inverse", ErrNotUnitary, g.FullName())
+	case DaggerGate:
+		return g.inner, nil
+	case ControlledGate:
+		// controlling commutes with inverting, and keeps the cheap controlled path
+		inner, err := Dagger(g.inner)
+		if err != nil {
+			return nil, err
+		}
+		return ControlledGate{inner: inner, state: g.state}, nil
+	}
+
+	data := g.Data()
+	matrix := NewMatrix(data.Cols, data.Rows)
+	for i := range data.Data {
+		for j := range data.Data[i] {
+			matrix.Data[j][i] = cmplx.Conj(data.Data[i][j])
+		}
+	}
+	return DaggerGate{
+		Gate: Gate{
+			Matrix: matrix,
+			name:   g.Name() + "†",
+		},
+		inner:   g,
+		example: g.Example() + "'",
+	}, nil
+}
+
+type ControlledGate struct {
+	inner GateInterface
+	// one '1' or '0' per control wire, what it has to read for inner to act
+	state string
+	// set for controlled gates with a name of their own, like cswap0,1,2
+	example string
+}
+
+func (g ControlledGate) WiresNeeded() int {
+	return len(g.state) + g.inner.WiresNeeded()
+}
+
+func (g ControlledGate) Example() string {
+	if g.example != "" {
+		return g.example
+	}
+	controls := make([]string, len(g.state))
+	for j := range g.state {
+		controls[j] = fmt.Sprint(j)
+		if g.state[j] == '0' {
+			controls[j] = "~" + controls[j]
+		}
+	}
+	return fmt.Sprintf("c(%s)%s", strings.Join(controls, ","), shiftExampleWires(g.inner.Example(), len(g.state)))
+}
+
+func (g ControlledGate) FullName() string {
+	if len(g.state) == 1 {
+		return "Controlled-" + g.inner.FullName()
+	}
+	return fmt.Sprintf("%d-Controlled-%s", len(g.state), g.inner.FullName())
+}
+
+func (g ControlledGate) Name() string {
+	return strings.Repeat("C", len(g.state)) + g.inner.Name()
+}
+
+// the full matrix, inner acting on the block whose control bits read state. executors
+// that can skip the other blocks use the inner gate directly
+func (g ControlledGate) Data() Matrix {
+	inner := g.inner.Data()
+	k := len(g.state)
+	dim := inner.Rows << k
+	matrix := Identity(dim).Data()
+	fires := 0
+	for _, bit := range g.state {
+		fires = fires<<1 | int(bit-'0')
+	}
+	start := fires * inner.Rows
+	for r := range inner.Data {
+		for c := range inner.Data[r] {
+			matrix.Data[start+r][start+c] = inner.Data[r][c]
+		}
+	}
+	return matrix
+}
+
+// g acting only when its control wires read state, one '1' or '0' per control wire
+// ("10" is a control on |1⟩ then one on |0⟩). the control wires come before g's own.
+// written c(0,~1)g or ctrl@negctrl@g in circuit strings
+func Controlled(g GateInterface, state string) (GateInterface, error) {
+	if state == "" || strings.Trim(state, "01") != "" {
+		return nil, fmt.Errorf("%w: control state %q", ErrInvalidArgument, state)
+	}
+	switch g := g.(type) {
+	case MeasureGate, ResetGate, ChannelInterface:
+		return nil, fmt.Errorf("%w: %s can't be controlled", ErrNotUnitary, g.FullName())
+	case ControlledGate:
+		// controls of controls are just more controls
+		return ControlledGate{inner: g.inner, state: state + g.state}, nil
+	}
+	return ControlledGate{inner: g, state: state}, nil
+}
+
+// an example's wires moved up by n, so control wires fit in front of them
+func shiftExampleWires(example string, n int) string {
+	match := exampleWiresRegex.FindStringSubmatchIndex(example)
+	if match == nil {
+		return example
+	}
+	wires := strings.Split(example[match[2]:match[3]], ",")
+	for i, wire := range wires {
+		w, _ := strconv.Atoi(wire)
+		wires[i] = strconv.Itoa(w + n)
+	}
+	return example[:match[2]] + strings.Join(wires, ",") + example[match[3]:]
+}
@@ -1,6 +1,13 @@
 package quantum
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strings"
+	"testing"
+)
 
 // dummy test to basically see the tests suite working
 func TestGatesIdentity(t *testing.T) {
@@ -18,3 +25,399 @@ func TestGatesIdentity(t *testing.T) {
 		t.Errorf("gate should have 1s on the diagonal")
 	}
 }
+
+func TestUGate(t *testing.T) {
+	same := func(a, b Matrix) bool {
+		for i := range a.Data {
+			for j := range a.Data[i] {
+				if cmplx.Abs(a.Data[i][j]-b.Data[i][j]) > 1e-12 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	tests := []struct {
+		gate string
+		want GateInterface
+	}{
+		{"u0(pi, 0, pi)", PauliX()},
+		{"u0(pi/2,0,pi)", Hadamard()},
+		{"u0(0,0,pi/2)", S()},
+		{"u0(0, 0, pi/4)", T()},
+		{"cu0,1(pi,0,pi)", CNOT()},
+		{"cu0,1(0,0,pi)", CZ()},
+	}
+	for _, tt := range tests {
+		c, err := NewCircuit([]string{tt.gate})
+		if err != nil {
+			t.Fatalf("%q: %v", tt.gate, err)
+		}
+		if !same(c.Gates[0].Gate.Data(), tt.want.Data()) {
+			t.Errorf("%q isn't %s", tt.gate, tt.want.FullName())
+		}
+	}
+
+	// every argument is evaluated on its own
+	c, err := NewCircuit([]string{"u0(2*(1+1), -pi/2, (3-1)*2)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := c.Gates[0].Gate.(UGate); g.theta != 4 || g.phi != -math.Pi/2 || g.lambda != 4 {
+		t.Errorf("parsed arguments %v, %v, %v", g.theta, g.phi, g.lambda)
+	}
+
+	for input, want := range map[string]string{
+		"u0(pi,0)":       "takes 3 argument(s), got 2",
+		"u0(pi,0,pi,0)":  "takes 3 argument(s), got 4",
+		"u0(pi,0,pi/)":   `argument 3 of u, "pi/"`,
+		"cu0,1(pi,,0)":   `argument 2 of cu, ""`,
+		"rx0(pi/2,pi/2)": "takes 1 argument(s), got 2",
+	} {
+		_, err := NewCircuit([]string{input})
+		if !errors.Is(err, ErrInvalidArgument) || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected an error mentioning %q, got %v", input, want, err)
+		}
+	}
+}
+
+func TestDagger(t *testing.T) {
+	// g† g = I for every registered unitary
+	for _, spec := range GateSpecs() {
+		params := []float64{0.3, 0.7, 1.1}[:spec.Params]
+		gate, err := spec.New(params, max(spec.Wires, 2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		dagger, err := Dagger(gate)
+		switch gate.(type) {
+		case MeasureGate, ResetGate, ChannelInterface:
+			if !errors.Is(err, ErrNotUnitary) {
+				t.Errorf("%s: expected ErrNotUnitary, got %v", spec.Name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", spec.Name, err)
+		}
+		a, b := dagger.Data(), gate.Data()
+		product := a.MustMultiply(&b)
+		for i := range product.Data {
+			for j := range product.Data[i] {
+				want := complex(0, 0)
+				if i == j {
+					want = 1
+				}
+				if cmplx.Abs(product.Data[i][j]-want) > 1e-12 {
+					t.Fatalf("%s: g† g isn't the identity", spec.Name)
+				}
+			}
+		}
+		if again, _ := Dagger(dagger); kindOf(again) != kindOf(gate) {
+			t.Errorf("%s: the inverse of the inverse is %s", spec.Name, again.FullName())
+		}
+	}
+}
+
+func TestInverseModifier(t *testing.T) {
+	same := func(a, b string) {
+		t.Helper()
+		c, err := NewCircuit([]string{a, b})
+		if err != nil {
+			t.Fatal(err)
+		}
+		x, y := c.Gates[0].Gate.Data(), c.Gates[1].Gate.Data()
+		for i := range x.Data {
+			for j := range x.Data[i] {
+				if cmplx.Abs(x.Data[i][j]-y.Data[i][j]) > 1e-12 {
+					t.Fatalf("%q and %q differ", a, b)
+				}
+			}
+		}
+	}
+	same("t0'", "tdg0")
+	same("inv(t0)", "u0(0,0,-pi/4)")
+	same("rz0(pi/4)'''", "rz0(-pi/4)")
+	same("inv(s0)", "sdg0")
+	same("crz0,1(pi/3)'", "crz0,1(-pi/3)")
+	same("inv(u0(1, 2, 3))", "u0(-1,-3,-2)")
+	same("t0''", "t0")
+
+	c, err := NewCircuit([]string{"t0'", "sdg1", "x1'?c0==1", "inv(crx0,1(pi))", "m0->0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := c.Gates[0].Gate.Name(); name != "T†" {
+		t.Errorf("t0' is named %q", name)
+	}
+	if c.Gates[2].Condition == nil {
+		t.Errorf("condition after the inverse mark dropped")
+	}
+	if drawStyleOf(c.Gates[3].Gate) != DrawControlled {
+		t.Errorf("inverted controlled gate should still draw controls")
+	}
+
+	for input, want := range map[string]error{
+		"m0->0'":       ErrNotUnitary,
+		"inv(reset0)":  ErrNotUnitary,
+		"depol0(0.1)'": ErrNotUnitary,
+		"inv(t0)(t1)":  ErrUnknownGate,
+		"inv(nope0)":   ErrUnknownGate,
+	} {
+		if _, err := NewCircuit([]string{input}); !errors.Is(err, want) {
+			t.Errorf("%q: expected %v, got %v", input, want, err)
+		}
+	}
+
+	// S† stays clifford
+	c, _ = NewCircuit([]string{"h0", "sdg0", "cnot0,1", "s1'", "h1'"})
+	if !c.IsClifford() {
+		t.Fatal("circuit with S† should be clifford")
+	}
+	want := runCircuit(t, []string{"h0", "sdg0", "cnot0,1", "s1'", "h1'"}, BackendStateVector)
+	got := runCircuit(t, []string{"h0", "sdg0", "cnot0,1", "s1'", "h1'"}, BackendStabilizer)
+	for key, p := range want.Probabilities {
+		if math.Abs(got.Probabilities[key]-p) > 1e-9 {
+			t.Errorf("stabilizer gives %v, state vector %v", got.Probabilities, want.Probabilities)
+		}
+	}
+}
+
+func TestControlModifier(t *testing.T) {
+	same := func(a, b string) {
+		t.Helper()
+		c, err := NewCircuit([]string{a, b})
+		if err != nil {
+			t.Fatal(err)
+		}
+		x, y := c.Gates[0], c.Gates[1]
+		if fmt.Sprint(x.Wires) != fmt.Sprint(y.Wires) {
+			t.Fatalf("%q is on wires %v, %q on %v", a, x.Wires, b, y.Wires)
+		}
+		for i, row := range x.Gate.Data().Data {
+			for j := range row {
+				if cmplx.Abs(row[j]-y.Gate.Data().Data[i][j]) > 1e-12 {
+					t.Fatalf("%q and %q differ", a, b)
+				}
+			}
+		}
+	}
+	same("c(0)x1", "cnot0,1")
+	same("ctrl@z0,1", "cz0,1")
+	same("c(0,1)x2", "toff0,1,2")
+	same("ctrl(2)@z0,1,2", "ccz0,1,2")
+	same("ctrl@cx0,1,2", "ccx0,1,2")
+	same("c(0)ry1(pi/3)", "cry0,1(pi/3)")
+	same("ctrl@negctrl@x0,1,2", "c(0,~1)x2")
+	same("c(0)s1'", "c(0)sdg1")
+	same("inv(c(0)s1)", "c(0)sdg1")
+
+	// ~ controls fire on |0⟩
+	for gates, want := range map[string]string{
+		"c(~0)x1":           "01",
+		"x0 c(~0)x1":        "10",
+		"x0 negctrl@x0,1":   "10",
+		"x1 c(1,~0)x2":      "011",
+		"x0 c(0,~2)swap1,3": "1000",
+	} {
+		c, err := NewCircuit(strings.Split(gates, " "))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := c.ExecuteToBarrier(len(c.Gates))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(result.Probabilities[want]-1) > 1e-9 {
+			t.Errorf("%q: expected |%s⟩, got %v", gates, want, result.Probabilities)
+		}
+	}
+
+	c, err := NewCircuit([]string{"c(3,~0)ry1(pi/2)?c0==1", "m0->0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gate := c.Gates[0]
+	if fmt.Sprint(gate.Wires) != "[3 0 1]" || gate.Condition == nil {
+		t.Errorf("parsed to wires %v, condition %v", gate.Wires, gate.Condition)
+	}
+	if gate.Gate.Name() != "CCRy(1.57)" || gate.Gate.FullName() != "2-Controlled-Rotate-Y" {
+		t.Errorf("named %q, %q", gate.Gate.Name(), gate.Gate.FullName())
+	}
+	if drawStyleOf(gate.Gate) != DrawControlled {
+		t.Errorf("controlled gate should draw controls")
+	}
+	if _, err := NewCircuit([]string{gate.Gate.Example()}); err != nil {
+		t.Errorf("example %q doesn't parse: %v", gate.Gate.Example(), err)
+	}
+
+	for input, want := range map[string]error{
+		"c(0)x0":          ErrDuplicateWire,
+		"c(0,0)x1":        ErrDuplicateWire,
+		"c(a)x1":          ErrInvalidWireFormat,
+		"c()x1":           ErrInvalidWireFormat,
+		"ctrl@x0":         ErrInvalidWireFormat,
+		"ctrl(0)@x0,1":    ErrInvalidArgument,
+		"ctrl@m0->0":      ErrInvalidWireFormat,
+		"c(0)m1->1":       ErrNotUnitary,
+		"c(0)reset1":      ErrNotUnitary,
+		"c(0)depol1(0.1)": ErrNotUnitary,
+		"c(0)nope1":       ErrUnknownGate,
+	} {
+		if _, err := NewCircuit([]string{input}); !errors.Is(err, want) {
+			t.Errorf("%q: expected %v, got %v", input, want, err)
+		}
+	}
+
+	// a single control on X or Z stays clifford, negative or not
+	clifford := []string{"h0", "ctrl@x0,1", "negctrl@z1,2", "c(~2)x0", "h2"}
+	c, _ = NewCircuit(clifford)
+	if !c.IsClifford() {
+		t.Fatal("circuit of CX and CZ should be clifford")
+	}
+	want := runCircuit(t, clifford, BackendStateVector)
+	got := runCircuit(t, clifford, BackendStabilizer)
+	for key, p := range want.Probabilities {
+		if math.Abs(got.Probabilities[key]-p) > 1e-9 {
+			t.Errorf("stabilizer gives %v, state vector %v", got.Probabilities, want.Probabilities)
+		}
+	}
+	if c, _ = NewCircuit([]string{"c(0,1)x2"}); c.IsClifford() {
+		t.Errorf("toffoli through the modifier isn't clifford")
+	}
+}
+
+func TestWideControlledGatesAcrossBackends(t *testing.T) {
+	gates := []string{"h0", "h1", "ry2(0.4)", "h3", "rx4(1.1)", "h5",
+		"c(5,~0,3)swap1,4", "ctrl(2)@negctrl@ry0,2,4,5(pi/3)", "c(1,2,3,4)crx0,5(0.8)", "c(~5)u2(1,2,3)",
+		// hadamards turn the coherences into probabilities
+		"h0", "h2", "h4"}
+	want := runCircuit(t, gates, BackendStateVector)
+	for _, backend := range []string{BackendDensity, BackendMPS} {
+		got := runCircuit(t, gates, backend)
+		for key, p := range want.Probabilities {
+			if math.Abs(got.Probabilities[key]-p) > 1e-9 {
+				t.Fatalf("%s: P(%s) = %v, want %v", backend, key, got.Probabilities[key], p)
+			}
+		}
+	}
+	mps := runCircuit(t, gates, BackendMPS)
+	if !sameResult(mps, want) {
+		t.Errorf("mps state differs from the state vector")
+	}
+}
+
+func TestGatesAreUnitary(t *testing.T) {
+	for _, spec := range GateSpecs() {
+		sample, _ := spec.Sample()
+		switch sample.(type) {
+		case MeasureGate, ResetGate, ChannelInterface:
+			continue
+		}
+		for _, params := range [][]float64{spec.arguments(), []float64{-0.4, 2.9, 5.1}[:spec.Params]} {
+			gate, err := spec.New(params, max(spec.Wires, 2))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// rows of a unitary are orthonormal
+			u := gate.Data()
+			for i := range u.Data {
+				for j := range u.Data {
+					var dot complex128
+					for k := range u.Data[i] {
+						dot += u.Data[i][k] * cmplx.Conj(u.Data[j][k])
+					}
+					want := complex(0, 0)
+					if i == j {
+						want = 1
+					}
+					if cmplx.Abs(dot-want) > 1e-12 {
+						t.Fatalf("%s(%v) isn't unitary", spec.Name, params)
+					}
+				}
+			}
+			if gate.FullName() == "" || gate.Example() == "" {
+				t.Errorf("%s has no full name or example", spec.Name)
+			}
+		}
+	}
+}
+
+func TestStandardGates(t *testing.T) {
+	// a and b are the same operator, up to a global phase when phase is false
+	same := func(a, b string, phase bool) {
+		t.Helper()
+		x := unitaryOf(t, a)
+		y := unitaryOf(t, b)
+		ratio := complex(1, 0)
+		if !phase {
+			for i := range x.Data {
+				if cmplx.Abs(y.Data[i][0]) > 1e-9 {
+					ratio = x.Data[i][0] / y.Data[i][0]
+					break
+				}
+			}
+		}
+		for i := range x.Data {
+			for j := range x.Data[i] {
+				if cmplx.Abs(x.Data[i][j]-ratio*y.Data[i][j]) > 1e-9 {
+					t.Fatalf("%q and %q differ", a, b)
+				}
+			}
+		}
+	}
+	same("sx0 sx0", "x0", true)
+	same("sx0 sxdg0", "i0", true)
+	same("sx0", "h0 s0 h0", true)
+	same("p0", "s0", true)
+	same("p0(pi/2)", "s0", true)
+	same("p0(pi/4)", "t0", true)
+	same("p0(-pi/4)", "tdg0", true)
+	same("p0(0.7)", "u0(0,0,0.7)", true)
+	same("cp0,1(pi)", "cz0,1", true)
+	same("cp0,1(0.7)", "c(0)p1(0.7)", true)
+	same("cy0,1", "c(0)y1", true)
+	same("ch0,1", "c(0)h1", true)
+	same("cswap0,1,2", "c(0)swap1,2", true)
+	same("fredkin0,1,2", "cnot2,1 toff0,1,2 cnot2,1", true)
+	same("iswap0,1", "cz0,1 swap0,1 s0 s1", true)
+	same("ecr0,1", "h1 s0 s1 cz0,1 h1 x0", false)
+	same("rxx0,1(0.7)", "h0 h1 rzz0,1(0.7) h0 h1", true)
+	same("ryy0,1(0.7)", "rx0(pi/2) rx1(pi/2) rzz0,1(0.7) rx0(-pi/2) rx1(-pi/2)", true)
+	same("rzz0,1(0.7)", "cnot0,1 rz1(0.7) cnot0,1", true)
+	same("gphase0(0.7)", "p0(0.7) x0 p0(0.7) x0", true)
+	same("c(0)gphase1(0.7)", "p0(0.7) i1", true)
+
+	c, err := NewCircuit([]string{"p0(pi/3)", "cp0,1(pi/3)", "rxx0,1(pi/3)", "gphase0(pi/3)", "cswap0,1,2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"P(1.05)", "CP(1.05)", "Rxx(1.05)", "GPh(1.05)", "CSWAP"} {
+		if name := c.Gates[i].Gate.Name(); name != want {
+			t.Errorf("gate %d is named %q, want %q", i, name, want)
+		}
+	}
+	if drawStyleOf(c.Gates[1].Gate) != DrawControlled || drawStyleOf(c.Gates[4].Gate) != DrawControlled {
+		t.Errorf("cp and cswap should draw their controls")
+	}
+	if drawStyleOf(c.Gates[2].Gate) != DrawBox {
+		t.Errorf("rxx should draw as a box on both wires")
+	}
+
+	if err := RegisterGate(GateSpec{Name: "twoargs", Wires: 1, Params: 2, Defaults: []float64{1}, New: rotationGate(PhaseShift)}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for a short default list, got %v", err)
+	}
+}
+
+func unitaryOf(t *testing.T, gates string) Matrix {
+	t.Helper()
+	c, err := NewCircuit(strings.Split(gates, " "))
+	if err != nil {
+		t.Fatalf("%q: %v", gates, err)
+	}
+	u, err := c.Unitary()
+	if err != nil {
+		t.Fatalf("%q: %v", gates, err)
+	}
+	return u
+}
@@ -0,0 +1,160 @@
+package quantum
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// starting state of a circuit, either one single qubit state per wire or a full
+// amplitude vector. wires past the ones it covers start in |0⟩
+type InitialState struct {
+	// one of 0 1 + - r l per wire, r and l being |+i⟩ and |-i⟩
+	Wires string
+	// normalized amplitudes over the first log2(len) wires, used when Wires is empty
+	Amplitudes []complex128
+}
+
+// single qubit amplitudes for each product state label
+var initialLabels = map[rune][2]complex128{
+	'0': {1, 0},
+	'1': {0, 1},
+	'+': {complex(1/math.Sqrt2, 0), complex(1/math.Sqrt2, 0)},
+	'-': {complex(1/math.Sqrt2, 0), complex(-1/math.Sqrt2, 0)},
+	'r': {complex(1/math.Sqrt2, 0), complex(0, 1/math.Sqrt2)},
+	'l': {complex(1/math.Sqrt2, 0), complex(0, -1/math.Sqrt2)},
+}
+
+// parses a product state like |0+1-⟩ or a bare bitstring like 0101, wire 0 first
+func ParseInitialState(s string) (*InitialState, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "|")
+	s = strings.TrimSuffix(strings.TrimSuffix(s, ">"), "⟩")
+	if s == "" {
+		return nil, fmt.Errorf("%w: no wires", ErrInvalidInitialState)
+	}
+	for _, r := range s {
+		if _, ok := initialLabels[r]; !ok {
+			return nil, fmt.Errorf("%w: %q is not one of 0 1 + - r l", ErrInvalidInitialState, r)
+		}
+	}
+	if len(s)-1 > maxParsedWire() {
+		return nil, fmt.Errorf("%w, max: %d", ErrTooManyWires, maxParsedWire())
+	}
+	return &InitialState{Wires: s}, nil
+}
+
+// reads an amplitude vector file, one complex number like 0.5 or 0.5-0.5i per field
+func LoadInitialState(path string) (*InitialState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAmplitudes(string(data))
+}
+
+// parses and validates whitespace separated amplitudes, wire 0 is the top bit of the index
+func ParseAmplitudes(s string) (*InitialState, error) {
+	fields := strings.Fields(s)
+	amplitudes := make([]complex128, len(fields))
+	for i, field := range fields {
+		amplitude, err := strconv.ParseComplex(field, 128)
+		if err != nil {
+			return nil, fmt.Errorf("%w: amplitude %d: %q is not a number", ErrInvalidInitialState, i, field)
+		}
+		amplitudes[i] = amplitude
+	}
+	state := &InitialState{Amplitudes: amplitudes}
+	if err := state.Validate(); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// checks labels, or the amplitude vector's length and norm
+func (s *InitialState) Validate() error {
+	if s.Wires != "" {
+		_, err := ParseInitialState(s.Wires)
+		return err
+	}
+	n := len(s.Amplitudes)
+	if n < 2 || n&(n-1) != 0 {
+		return fmt.Errorf("%w: %d amplitudes, want a power of 2", ErrInvalidInitialState, n)
+	}
+	if n > 1<<(maxWires+1) {
+		return fmt.Errorf("%w, max: %d", ErrTooManyWires, maxWires)
+	}
+	norm := 0.0
+	for _, amplitude := range s.Amplitudes {
+		if cmplx.IsNaN(amplitude) || cmplx.IsInf(amplitude) {
+			return fmt.Errorf("%w: amplitudes must be finite", ErrInvalidInitialState)
+		}
+		norm += real(amplitude)*real(amplitude) + imag(amplitude)*imag(amplitude)
+	}
+	if math.Abs(norm-1) > 1e-6 {
+		return fmt.Errorf("%w: squared amplitudes sum to %g, want 1", ErrInvalidInitialState, norm)
+	}
+	return nil
+}
+
+// how many wires the state covers
+func (s *InitialState) NumWires() int {
+	if s.Wires != "" {
+		return len(s.Wires)
+	}
+	wires := 0
+	for 1<<wires < len(s.Amplitudes) {
+		wires++
+	}
+	return wires
+}
+
+// whether the state is one single qubit state per wire
+func (s *InitialState) IsProduct() bool {
+	return s.Wires != ""
+}
+
+// ket drawn at the start of a wire
+func (s *InitialState) Label(wire int) string {
+	if s == nil || wire >= s.NumWires() {
+		return "|0⟩"
+	}
+	if s.Wires == "" {
+		return "|ψ⟩"
+	}
+	return fmt.Sprintf("|%c⟩", s.Wires[wire])
+}
+
+// state vector over numQubits wires, |0...0⟩ for a nil state
+func (s *InitialState) vector(numQubits int) []complex128 {
+	state := make([]complex128, 1<<numQubits)
+	if s == nil {
+		state[0] = 1
+		return state
+	}
+
+	// the covered wires are the top bits, the rest stay |0⟩
+	shift := numQubits - s.NumWires()
+	if s.Wires == "" {
+		for i, amplitude := range s.Amplitudes {
+			state[i<<shift] = amplitude
+		}
+		return state
+	}
+	covered := []complex128{1}
+	for _, r := range s.Wires {
+		ket := initialLabels[r]
+		next := make([]complex128, 0, 2*len(covered))
+		for _, amplitude := range covered {
+			next = append(next, amplitude*ket[0], amplitude*ket[1])
+		}
+		covered = next
+	}
+	for i, amplitude := range covered {
+		state[i<<shift] = amplitude
+	}
+	return state
+}
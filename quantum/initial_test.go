@@ -0,0 +1,155 @@
+package quantum
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestParseInitialState(t *testing.T) {
+	for input, want := range map[string]string{
+		"|0+1->": "0+1-",
+		"|rl⟩":   "rl",
+		"0101":   "0101",
+		" |1> ":  "1",
+	} {
+		state, err := ParseInitialState(input)
+		if err != nil {
+			t.Fatalf("%q: %v", input, err)
+		}
+		if state.Wires != want {
+			t.Errorf("%q: got wires %q, want %q", input, state.Wires, want)
+		}
+	}
+
+	for _, input := range []string{"", "|⟩", "|0x1⟩", "012"} {
+		if _, err := ParseInitialState(input); !errors.Is(err, ErrInvalidInitialState) {
+			t.Errorf("%q: expected ErrInvalidInitialState, got %v", input, err)
+		}
+	}
+}
+
+func TestParseAmplitudes(t *testing.T) {
+	state, err := ParseAmplitudes("0.7071067811865476\n0\n0\n0+0.7071067811865476i\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.NumWires() != 2 || state.Amplitudes[3] != complex(0, 0.7071067811865476) {
+		t.Errorf("unexpected state %v", state.Amplitudes)
+	}
+
+	for _, input := range []string{"1", "1 0 0", "0.5 0.5", "1 zero", "NaN 0"} {
+		if _, err := ParseAmplitudes(input); !errors.Is(err, ErrInvalidInitialState) {
+			t.Errorf("%q: expected ErrInvalidInitialState, got %v", input, err)
+		}
+	}
+}
+
+func TestInitialStateStartsCircuit(t *testing.T) {
+	c, err := NewCircuit([]string{"cnot0,1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Initial, _ = ParseInitialState("|+0⟩")
+	result, err := c.ExecuteToBarrier(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"00", "11"} {
+		if math.Abs(result.Probabilities[key]-0.5) > 1e-9 {
+			t.Errorf("P(%s) = %v, want 0.5", key, result.Probabilities[key])
+		}
+	}
+
+	// wires the state covers count even when no gate touches them, later ones start in |0⟩
+	c, _ = NewCircuit([]string{"x0", "x2"})
+	c.Initial, _ = ParseInitialState("11")
+	result, err = c.ExecuteToBarrier(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.NumQubits() != 3 || math.Abs(result.Probabilities["011"]-1) > 1e-9 {
+		t.Errorf("unexpected probabilities over %d wires: %v", c.NumQubits(), result.Probabilities)
+	}
+}
+
+func TestInitialStateAcrossBackends(t *testing.T) {
+	gates := []string{"h0", "s1", "cnot0,1", "h2", "cz1,2", "h1", "cnot2,0"}
+	for _, init := range []string{"0+1", "r-l", "l1+", "-r0"} {
+		c, err := NewCircuit(gates)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Initial, _ = ParseInitialState(init)
+		want, err := c.ExecuteToBarrier(len(gates))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, backend := range []string{BackendDensity, BackendTrajectory, BackendStabilizer, BackendMPS} {
+			c.Backend = backend
+			c.Trajectories = 2
+			got, err := c.ExecuteToBarrier(len(gates))
+			if err != nil {
+				t.Fatalf("%s %s: %v", init, backend, err)
+			}
+			for key, p := range want.Probabilities {
+				if math.Abs(got.Probabilities[key]-p) > 1e-9 {
+					t.Errorf("%s %s: P(%s) = %v, want %v", init, backend, key, got.Probabilities[key], p)
+				}
+			}
+		}
+	}
+}
+
+func TestAmplitudeInitialState(t *testing.T) {
+	// (|000⟩ + 2i|011⟩ - 2|101⟩) / 3, wider than the circuit so the mps splits it
+	initial := &InitialState{Amplitudes: []complex128{1.0 / 3, 0, 0, 2i / 3, 0, -2.0 / 3, 0, 0}}
+	if err := initial.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ := NewCircuit([]string{"h1", "cnot1,3"})
+	c.Initial = initial
+	want, err := c.ExecuteToBarrier(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.NumQubits() != 4 {
+		t.Fatalf("got %d wires, want 4", c.NumQubits())
+	}
+
+	c.Backend = BackendMPS
+	got, err := c.ExecuteToBarrier(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, amplitude := range want.StateVector {
+		if cmplx.Abs(got.StateVector[key]-amplitude) > 1e-9 {
+			t.Errorf("mps amplitude of %s = %v, want %v", key, got.StateVector[key], amplitude)
+		}
+	}
+
+	c.Backend = BackendStabilizer
+	if _, err := c.ExecuteToBarrier(2); !errors.Is(err, ErrProductStateOnly) {
+		t.Errorf("expected ErrProductStateOnly, got %v", err)
+	}
+}
+
+func TestInitialStateLabels(t *testing.T) {
+	var none *InitialState
+	if none.Label(0) != "|0⟩" {
+		t.Errorf("nil state labelled %s", none.Label(0))
+	}
+	product, _ := ParseInitialState("0+r")
+	for wire, want := range []string{"|0⟩", "|+⟩", "|r⟩", "|0⟩"} {
+		if got := product.Label(wire); got != want {
+			t.Errorf("wire %d labelled %s, want %s", wire, got, want)
+		}
+	}
+	vector := &InitialState{Amplitudes: []complex128{0, 1, 0, 0}}
+	if vector.Label(1) != "|ψ⟩" || vector.Label(2) != "|0⟩" {
+		t.Errorf("amplitude state labelled %s %s", vector.Label(1), vector.Label(2))
+	}
+}
@@ -0,0 +1,128 @@
+package quantum
+
+import (
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+// dense complex linear algebra for the tensor network code, kept to plain
+// [][]complex128 so it doesn't drag in a dependency
+
+// singular value decomposition A = U diag(s) Vh with s sorted descending, for an
+// m x n matrix U is m x k, Vh is k x n and k = min(m, n)
+func svd(a [][]complex128) (u [][]complex128, s []float64, vh [][]complex128) {
+	m := len(a)
+	n := len(a[0])
+	if m < n {
+		// A† = U' S V'†, so A = V' S U'†
+		up, sp, vhp := svd(conjugateTranspose(a))
+		return conjugateTranspose(vhp), sp, conjugateTranspose(up)
+	}
+
+	// one-sided Jacobi: rotate column pairs of A until they're orthogonal, the
+	// same rotations applied to the identity build V
+	w := make([][]complex128, m)
+	for i := range a {
+		w[i] = append([]complex128(nil), a[i]...)
+	}
+	v := make([][]complex128, n)
+	for i := range v {
+		v[i] = make([]complex128, n)
+		v[i][i] = 1
+	}
+
+	for sweep := 0; sweep < 60; sweep++ {
+		rotated := false
+		for p := 0; p < n-1; p++ {
+			for q := p + 1; q < n; q++ {
+				alpha, beta := 0.0, 0.0
+				gamma := complex(0, 0)
+				for i := 0; i < m; i++ {
+					alpha += real(w[i][p])*real(w[i][p]) + imag(w[i][p])*imag(w[i][p])
+					beta += real(w[i][q])*real(w[i][q]) + imag(w[i][q])*imag(w[i][q])
+					gamma += cmplx.Conj(w[i][p]) * w[i][q]
+				}
+				g := cmplx.Abs(gamma)
+				if g == 0 || g <= 1e-15*math.Sqrt(alpha*beta) {
+					continue
+				}
+				rotated = true
+
+				// the phase of gamma makes the pair real, then it's a plain Jacobi rotation
+				phase := cmplx.Conj(gamma) / complex(g, 0)
+				zeta := (beta - alpha) / (2 * g)
+				t := 1 / (math.Abs(zeta) + math.Sqrt(1+zeta*zeta))
+				if zeta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(1+t*t)
+				sn := c * t
+				rotate := func(rows [][]complex128) {
+					for _, row := range rows {
+						up, uq := row[p], row[q]*phase
+						row[p] = complex(c, 0)*up - complex(sn, 0)*uq
+						row[q] = complex(sn, 0)*up + complex(c, 0)*uq
+					}
+				}
+				rotate(w)
+				rotate(v)
+			}
+		}
+		if !rotated {
+			break
+		}
+	}
+
+	// columns of AV are s_j u_j
+	order := make([]int, n)
+	norms := make([]float64, n)
+	for j := 0; j < n; j++ {
+		order[j] = j
+		for i := 0; i < m; i++ {
+			norms[j] += real(w[i][j])*real(w[i][j]) + imag(w[i][j])*imag(w[i][j])
+		}
+		norms[j] = math.Sqrt(norms[j])
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return norms[order[i]] > norms[order[j]]
+	})
+
+	u = make([][]complex128, m)
+	for i := range u {
+		u[i] = make([]complex128, n)
+	}
+	s = make([]float64, n)
+	vh = make([][]complex128, n)
+	for k, j := range order {
+		s[k] = norms[j]
+		for i := 0; i < m; i++ {
+			if norms[j] > 0 {
+				u[i][k] = w[i][j] / complex(norms[j], 0)
+			}
+		}
+		vh[k] = make([]complex128, n)
+		for i := 0; i < n; i++ {
+			vh[k][i] = cmplx.Conj(v[i][j])
+		}
+	}
+	return u, s, vh
+}
+
+// eigenvalues of a positive semidefinite Hermitian matrix such as ρ, which are its
+// singular values, sorted descending
+func psdEigenvalues(a [][]complex128) []float64 {
+	_, s, _ := svd(a)
+	return s
+}
+
+func conjugateTranspose(a [][]complex128) [][]complex128 {
+	out := make([][]complex128, len(a[0]))
+	for j := range out {
+		out[j] = make([]complex128, len(a))
+		for i := range a {
+			out[j][i] = cmplx.Conj(a[i][j])
+		}
+	}
+	return out
+}
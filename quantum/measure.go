@@ -0,0 +1,207 @@
+package quantum
+
+import (
+	"math"
+	"math/rand"
+)
+
+// number of classical bits the circuit writes to or reads from
+func (c *Circuit) NumCbits() int {
+	numCbits := 0
+	for _, gate := range c.Gates {
+		for _, cbit := range gate.Cbits {
+			if cbit >= numCbits {
+				numCbits = cbit + 1
+			}
+		}
+		if cbit := gate.Condition.maxBit(); cbit >= numCbits {
+			numCbits = cbit + 1
+		}
+	}
+	return numCbits
+}
+
+// measurements and resets pick a random branch of the state
+func measures(gate GateInterface) bool {
+	switch gate.(type) {
+	case MeasureGate, ResetGate:
+		return true
+	}
+	return false
+}
+
+// true if any gate measures a wire, resets count since they measure and reprepare
+func (c *Circuit) HasMeasurements() bool {
+	for _, gate := range c.Gates {
+		if measures(gate.Gate) {
+			return true
+		}
+	}
+	return false
+}
+
+// true if any gate resets a wire
+func (c *Circuit) HasResets() bool {
+	for _, gate := range c.Gates {
+		if _, ok := gate.Gate.(ResetGate); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// true if a reset before barrier n ran on a backend that keeps one pure state. the
+// reset then shows one sampled branch, with the wires entangled with it collapsed
+// too, where the density backend traces the wire out
+func (c *Circuit) sampledReset(atBarrier int, result Result) bool {
+	if result.DensityMatrix != nil {
+		return false
+	}
+	if backend, err := LookupBackend(c.Backend); err == nil && backend.Capabilities().AveragesMeasurements {
+		return false
+	}
+	for _, gate := range c.Gates[:atBarrier] {
+		if _, ok := gate.Gate.(ResetGate); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// true if the circuit has a measurement whose outcome SetOutcome can pick
+func (c *Circuit) hasChoosableOutcomes() bool {
+	for _, gate := range c.Gates {
+		if _, ok := gate.Gate.(MeasureGate); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// forces the measurement at gate index to read outcome. a reset's outcome isn't
+// choosable, it traces its wire out
+func (c *Circuit) SetOutcome(index, outcome int) error {
+	if index < 0 || index >= len(c.Gates) {
+		return ErrInvalidBarrier
+	}
+	if _, ok := c.Gates[index].Gate.(MeasureGate); !ok {
+		return ErrNotMeasurement
+	}
+	if outcome != 0 && outcome != 1 {
+		return ErrInvalidArgument
+	}
+	if c.Outcomes == nil {
+		c.Outcomes = make(map[int]int)
+	}
+	c.Outcomes[index] = outcome
+	c.ownSnapshots().dropAfter(index)
+	return nil
+}
+
+// drops any forced outcome at gate index and rolls it again
+func (c *Circuit) Reroll(index int) error {
+	if index < 0 || index >= len(c.Gates) {
+		return ErrInvalidBarrier
+	}
+	if _, ok := c.Gates[index].Gate.(MeasureGate); !ok {
+		return ErrNotMeasurement
+	}
+	delete(c.Outcomes, index)
+	if c.rolls == nil {
+		c.rolls = make(map[int]int64)
+	}
+	c.rolls[index]++
+	c.ownSnapshots().dropAfter(index)
+	return nil
+}
+
+// rng for the random operation at gate index; outcomes only depend on the seed and
+// the gate's position, so every prefix of the circuit rolls them the same way.
+// stream separates independent draws at the same gate (outcome, readout error, ...)
+func (c *Circuit) gateRand(index int, stream int64) *rand.Rand {
+	return rand.New(rand.NewSource(mixSeed(c.Seed + int64(index+1)*7919 + c.rolls[index]*104729 + stream*15485863)))
+}
+
+// splitmix64 finaliser, math/rand sources seeded a fixed distance apart give
+// correlated first draws, which would correlate the gates of every seeded run
+func mixSeed(seed int64) int64 {
+	z := uint64(seed) + 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return int64(z ^ (z >> 31))
+}
+
+// picks the outcome of the measurement at gate index given the chance of reading 1,
+// honouring SetOutcome and the seed. backends call this so every simulator rolls
+// the same branch for the same circuit
+func (c *Circuit) MeasurementOutcome(index int, probabilityOne float64) (int, error) {
+	if outcome, ok := c.Outcomes[index]; ok {
+		if (outcome == 1 && probabilityOne < 1e-12) || (outcome == 0 && probabilityOne > 1-1e-12) {
+			return 0, ErrImpossibleOutcome
+		}
+		return outcome, nil
+	}
+	if c.gateRand(index, 0).Float64() < probabilityOne {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// chance that wire reads 1
+func probabilityOfOne(state []complex128, numQubits, wire int) float64 {
+	m := wireMask(wire, numQubits)
+	p := 0.0
+	for i, amplitude := range state {
+		if i&m != 0 {
+			p += real(amplitude)*real(amplitude) + imag(amplitude)*imag(amplitude)
+		}
+	}
+	return p
+}
+
+// projects wire onto outcome and renormalises, p is the probability of that outcome
+func collapseWire(state []complex128, numQubits, wire, outcome int, p float64) {
+	m := wireMask(wire, numQubits)
+	scale := complex(1/math.Sqrt(p), 0)
+	for i := range state {
+		if (i&m != 0) == (outcome == 1) {
+			state[i] *= scale
+		} else {
+			state[i] = 0
+		}
+	}
+}
+
+// classical bit recorded for a measurement outcome at gate index, after the noise
+// model's readout error
+func (c *Circuit) RecordedOutcome(index, wire, outcome int) int {
+	return c.Noise.readout(wire, outcome, c.gateRand(index, 1))
+}
+
+// measures wire with the Born rule, collapsing the state in place
+func (c *Circuit) measureWire(state []complex128, numQubits, index, wire int) (int, error) {
+	p1 := probabilityOfOne(state, numQubits, wire)
+	outcome, err := c.MeasurementOutcome(index, p1)
+	if err != nil {
+		return 0, err
+	}
+	if outcome == 1 {
+		collapseWire(state, numQubits, wire, 1, p1)
+	} else {
+		collapseWire(state, numQubits, wire, 0, 1-p1)
+	}
+	return outcome, nil
+}
+
+// measures wire and flips it back to |0⟩ if it read 1, the rest of the state keeps
+// the branch consistent with that outcome
+func (c *Circuit) resetWire(state []complex128, numQubits, index, wire int) error {
+	outcome, err := c.measureWire(state, numQubits, index, wire)
+	if err != nil {
+		return err
+	}
+	if outcome == 1 {
+		applySingleQubitKernel(state, numQubits, PauliX().Data(), wire)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package quantum
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMeasurementCollapsesBellPair(t *testing.T) {
+	circuit, err := NewCircuit(strings.Split("h0 cnot0,1 m0->1 m1->0", " "))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for seed := int64(0); seed < 20; seed++ {
+		circuit.Seed = seed
+		result, err := circuit.ExecuteToBarrier(len(circuit.Gates))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := result.ClassicalRegister
+		if len(c) != 2 || c[0] != c[1] {
+			t.Fatalf("bell pair measurements should agree, got %v", c)
+		}
+		key := "00"
+		if c[0] == 1 {
+			key = "11"
+		}
+		if result.Probabilities[key] < 1-1e-9 {
+			t.Fatalf("state should collapse to %s, got %v", key, result.Probabilities)
+		}
+	}
+}
+
+func TestForcedOutcome(t *testing.T) {
+	circuit, err := NewCircuit(strings.Split("h0 m0->0 x1", " "))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, outcome := range []int{0, 1} {
+		if err := circuit.SetOutcome(1, outcome); err != nil {
+			t.Fatal(err)
+		}
+		// every prefix past the measurement sees the same branch
+		for barrier := 2; barrier <= 3; barrier++ {
+			result, err := circuit.ExecuteToBarrier(barrier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.ClassicalRegister[0] != outcome {
+				t.Errorf("barrier %d: c0 = %d, want %d", barrier, result.ClassicalRegister[0], outcome)
+			}
+		}
+	}
+
+	if err := circuit.SetOutcome(0, 1); !errors.Is(err, ErrNotMeasurement) {
+		t.Errorf("expected ErrNotMeasurement, got %v", err)
+	}
+
+	impossible, _ := NewCircuit(strings.Split("x0 m0->0", " "))
+	impossible.SetOutcome(1, 0)
+	if _, err := impossible.ExecuteToBarrier(2); !errors.Is(err, ErrImpossibleOutcome) {
+		t.Errorf("expected ErrImpossibleOutcome, got %v", err)
+	}
+}
+
+func TestReset(t *testing.T) {
+	tests := []struct {
+		gates string
+		// any of these branches is fine
+		want []string
+	}{
+		{"x0 reset0", []string{"0"}},
+		{"x0 x1 reset0", []string{"01"}},
+		// ancilla reused after being entangled, wire 0 keeps the branch it collapsed to
+		{"h0 cnot0,1 reset1 cnot0,1", []string{"00", "11"}},
+	}
+	for _, tt := range tests {
+		circuit, err := NewCircuit(strings.Split(tt.gates, " "))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for seed := int64(0); seed < 10; seed++ {
+			circuit.Seed = seed
+			result, err := circuit.ExecuteToBarrier(len(circuit.Gates))
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := 0.0
+			for _, key := range tt.want {
+				p = math.Max(p, result.Probabilities[key])
+			}
+			if p < 1-1e-9 {
+				t.Errorf("%q seed %d: got %v", tt.gates, seed, result.Probabilities)
+			}
+		}
+	}
+}
+
+func TestMeasurementBitIsCapped(t *testing.T) {
+	if _, err := NewCircuit([]string{"m0->999999999"}); !errors.Is(err, ErrTooManyCbits) {
+		t.Errorf("expected ErrTooManyCbits, got %v", err)
+	}
+	if _, err := NewCircuit([]string{"m0->99999999999999999999"}); !errors.Is(err, ErrInvalidWireFormat) {
+		t.Errorf("expected ErrInvalidWireFormat, got %v", err)
+	}
+	circuit, err := NewCircuit([]string{fmt.Sprintf("m0->%d", maxParsedWire())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if circuit.NumCbits() != maxParsedWire()+1 {
+		t.Errorf("register has %d bits", circuit.NumCbits())
+	}
+}
+
+func TestResetIsNotChoosable(t *testing.T) {
+	circuit, err := NewCircuit(strings.Split("h0 cnot0,1 reset1", " "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := circuit.SetOutcome(2, 1); !errors.Is(err, ErrNotMeasurement) {
+		t.Errorf("expected ErrNotMeasurement, got %v", err)
+	}
+	if err := circuit.Reroll(2); !errors.Is(err, ErrNotMeasurement) {
+		t.Errorf("expected ErrNotMeasurement, got %v", err)
+	}
+	if circuit.hasChoosableOutcomes() {
+		t.Error("a reset has no outcome to pick")
+	}
+
+	// the pure state backends show one branch and say so, ρ shows the traced out wire
+	for backend, sampled := range map[string]bool{
+		BackendStateVector: true,
+		BackendStabilizer:  true,
+		BackendDensity:     false,
+		BackendTrajectory:  false,
+	} {
+		circuit.Backend = backend
+		result, err := circuit.ExecuteToBarrier(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if circuit.sampledReset(3, result) != sampled {
+			t.Errorf("%s: sampled branch is %v, want %v", backend, !sampled, sampled)
+		}
+		if !sampled && (math.Abs(result.Probabilities["00"]-0.5) > 0.05 || math.Abs(result.Probabilities["10"]-0.5) > 0.05) {
+			t.Errorf("%s: expected wire 0 half and half, got %v", backend, result.Probabilities)
+		}
+	}
+	if circuit.sampledReset(2, Result{}) {
+		t.Error("no reset before barrier 2")
+	}
+}
@@ -0,0 +1,649 @@
+package quantum
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// matrix product state simulation
+//
+// the state is a chain of one tensor per site, A[l][s][r], with bonds between
+// neighbours. a product state has bond dimension 1 and every entangling gate can
+// grow the bond it acts across, so memory follows entanglement instead of doubling
+// per wire. bonds wider than the cap are cut at their smallest singular values and
+// the discarded weight is reported as the truncation error.
+//
+// gates act on a block of adjacent sites: wires further apart are swapped next to
+// each other first, and they stay where they were moved, so siteOf/wireAt track
+// which wire every site currently holds. the chain is kept in mixed canonical form
+// around center, which makes the cut singular values the real Schmidt values
+
+type mpsSite struct {
+	left, right int
+	// A[l][s][r] at (l*2+s)*right+r
+	data []complex128
+}
+
+type mps struct {
+	sites  []mpsSite
+	wireAt []int
+	siteOf []int
+	// sites left of center are left-canonical, sites right of it right-canonical
+	center int
+	// widest bond allowed
+	maxBond int
+	// discarded weight summed over every cut
+	truncation float64
+	// widest bond reached
+	widest int
+}
+
+// mps of |0...0⟩, every bond has dimension 1
+func newMPS(numQubits, maxBond int) *mps {
+	m := &mps{
+		sites:   make([]mpsSite, numQubits),
+		wireAt:  make([]int, numQubits),
+		siteOf:  make([]int, numQubits),
+		maxBond: maxBond,
+		widest:  1,
+	}
+	for i := range m.sites {
+		m.sites[i] = mpsSite{left: 1, right: 1, data: []complex128{1, 0}}
+		m.wireAt[i] = i
+		m.siteOf[i] = i
+	}
+	return m
+}
+
+func (m *mps) clone() *mps {
+	out := *m
+	out.sites = make([]mpsSite, len(m.sites))
+	for i, site := range m.sites {
+		out.sites[i] = mpsSite{left: site.left, right: site.right, data: append([]complex128(nil), site.data...)}
+	}
+	out.wireAt = append([]int(nil), m.wireAt...)
+	out.siteOf = append([]int(nil), m.siteOf...)
+	return &out
+}
+
+// starts the chain from an initial state, nil leaves it at |0...0⟩
+func (m *mps) prepare(initial *InitialState) {
+	if initial == nil {
+		return
+	}
+	if initial.IsProduct() {
+		for i, r := range initial.Wires {
+			ket := initialLabels[r]
+			m.sites[i].data = []complex128{ket[0], ket[1]}
+		}
+		return
+	}
+
+	// the amplitude vector is one block over the wires it covers
+	k := initial.NumWires()
+	m.moveCenter(0)
+	m.split(0, k, 1, len(initial.Amplitudes), 1, append([]complex128(nil), initial.Amplitudes...))
+}
+
+// singular values below this fraction of the largest are treated as zero
+const mpsCutoff = 1e-14
+
+// how many singular values survive the cutoff
+func keptValues(s []float64) int {
+	keep := 1
+	for keep < len(s) && s[keep] > mpsCutoff*s[0] {
+		keep++
+	}
+	return keep
+}
+
+// moves the orthogonality center to site k with exact SVDs
+func (m *mps) moveCenter(k int) {
+	for m.center < k {
+		site, next := m.sites[m.center], m.sites[m.center+1]
+		mat := make([][]complex128, site.left*2)
+		for i := range mat {
+			mat[i] = site.data[i*site.right : (i+1)*site.right]
+		}
+		u, s, vh := svd(mat)
+		keep := keptValues(s)
+
+		m.sites[m.center] = siteFromRows(u, site.left, keep)
+		// S Vh is absorbed into the next site
+		carried := make([][]complex128, keep)
+		for i := range carried {
+			carried[i] = make([]complex128, site.right)
+			for j := range carried[i] {
+				carried[i][j] = complex(s[i], 0) * vh[i][j]
+			}
+		}
+		m.sites[m.center+1] = multiplyLeft(carried, next)
+		m.center++
+	}
+	for m.center > k {
+		site, prev := m.sites[m.center], m.sites[m.center-1]
+		mat := make([][]complex128, site.left)
+		for i := range mat {
+			mat[i] = site.data[i*2*site.right : (i+1)*2*site.right]
+		}
+		u, s, vh := svd(mat)
+		keep := keptValues(s)
+
+		data := make([]complex128, 0, keep*2*site.right)
+		for i := 0; i < keep; i++ {
+			data = append(data, vh[i]...)
+		}
+		m.sites[m.center] = mpsSite{left: keep, right: site.right, data: data}
+		// U S is absorbed into the previous site
+		carried := make([][]complex128, site.left)
+		for i := range carried {
+			carried[i] = make([]complex128, keep)
+			for j := range carried[i] {
+				carried[i][j] = u[i][j] * complex(s[j], 0)
+			}
+		}
+		m.sites[m.center-1] = multiplyRight(prev, carried)
+		m.center--
+	}
+}
+
+// site from the first keep columns of a (left*2) x n matrix
+func siteFromRows(u [][]complex128, left, keep int) mpsSite {
+	data := make([]complex128, 0, left*2*keep)
+	for i := 0; i < left*2; i++ {
+		data = append(data, u[i][:keep]...)
+	}
+	return mpsSite{left: left, right: keep, data: data}
+}
+
+// C[l][s][r] = Σ_k M[l][k] A[k][s][r]
+func multiplyLeft(matrix [][]complex128, site mpsSite) mpsSite {
+	out := mpsSite{left: len(matrix), right: site.right, data: make([]complex128, len(matrix)*2*site.right)}
+	width := 2 * site.right
+	for l, row := range matrix {
+		for k, v := range row {
+			if v == 0 {
+				continue
+			}
+			for j := 0; j < width; j++ {
+				out.data[l*width+j] += v * site.data[k*width+j]
+			}
+		}
+	}
+	return out
+}
+
+// C[l][s][r] = Σ_k A[l][s][k] M[k][r]
+func multiplyRight(site mpsSite, matrix [][]complex128) mpsSite {
+	right := len(matrix[0])
+	out := mpsSite{left: site.left, right: right, data: make([]complex128, site.left*2*right)}
+	for ls := 0; ls < site.left*2; ls++ {
+		for k := 0; k < site.right; k++ {
+			v := site.data[ls*site.right+k]
+			if v == 0 {
+				continue
+			}
+			for r := 0; r < right; r++ {
+				out.data[ls*right+r] += v * matrix[k][r]
+			}
+		}
+	}
+	return out
+}
+
+// applies gate to the k adjacent sites from start, local[j] is the block position
+// of the gate's j-th wire
+func (m *mps) applyBlock(start, k int, gate Matrix, local []int) {
+	m.moveCenter(start)
+
+	// contract the block into theta[l][idx][r], the first site is the top bit of idx
+	left := m.sites[start].left
+	dim := 2
+	theta := append([]complex128(nil), m.sites[start].data...)
+	right := m.sites[start].right
+	for i := 1; i < k; i++ {
+		next := m.sites[start+i]
+		merged := make([]complex128, left*dim*2*next.right)
+		for li := 0; li < left*dim; li++ {
+			for b := 0; b < right; b++ {
+				v := theta[li*right+b]
+				if v == 0 {
+					continue
+				}
+				for j := 0; j < 2*next.right; j++ {
+					merged[li*2*next.right+j] += v * next.data[b*2*next.right+j]
+				}
+			}
+		}
+		theta = merged
+		dim *= 2
+		right = next.right
+	}
+
+	// the gate acts on idx for every pair of outer bonds
+	vec := make([]complex128, dim)
+	for l := 0; l < left; l++ {
+		for r := 0; r < right; r++ {
+			for idx := range vec {
+				vec[idx] = theta[(l*dim+idx)*right+r]
+			}
+			applyGateKernel(vec, k, gate, local)
+			for idx := range vec {
+				theta[(l*dim+idx)*right+r] = vec[idx]
+			}
+		}
+	}
+
+	m.split(start, k, left, dim, right, theta)
+}
+
+// splits theta[l][idx][r] back into the k sites from start, left to right, cutting
+// each bond to maxBond. the last site becomes the center
+func (m *mps) split(start, k, left, dim, right int, theta []complex128) {
+	for i := 0; i < k-1; i++ {
+		rest := dim / 2
+		mat := make([][]complex128, left*2)
+		for row := range mat {
+			mat[row] = theta[row*rest*right : (row+1)*rest*right]
+		}
+		u, s, vh := svd(mat)
+
+		keep := min(keptValues(s), m.maxBond)
+		total, discarded := 0.0, 0.0
+		for j, v := range s {
+			total += v * v
+			if j >= keep {
+				discarded += v * v
+			}
+		}
+		if total > 0 {
+			m.truncation += discarded / total
+		}
+		scale := math.Sqrt(total / (total - discarded))
+
+		m.sites[start+i] = siteFromRows(u, left, keep)
+		m.widest = max(m.widest, keep)
+		theta = make([]complex128, 0, keep*rest*right)
+		for j := 0; j < keep; j++ {
+			for _, v := range vh[j] {
+				theta = append(theta, complex(s[j]*scale, 0)*v)
+			}
+		}
+		left = keep
+		dim = rest
+	}
+	m.sites[start+k-1] = mpsSite{left: left, right: right, data: theta}
+	m.center = start + k - 1
+}
+
+// applies a gate to wires anywhere on the chain, swapping them next to each other first
+func (m *mps) apply(gate Matrix, wires []int) {
+	sites := make([]int, len(wires))
+	for i, wire := range wires {
+		sites[i] = m.siteOf[wire]
+	}
+	sort.Ints(sites)
+
+	// pull every wire down next to the lowest one
+	start := sites[0]
+	for j := 1; j < len(sites); j++ {
+		for site := sites[j]; site > start+j; site-- {
+			m.applyBlock(site-1, 2, SWAP().Data(), []int{0, 1})
+			a, b := m.wireAt[site-1], m.wireAt[site]
+			m.wireAt[site-1], m.wireAt[site] = b, a
+			m.siteOf[a], m.siteOf[b] = site, site-1
+		}
+	}
+
+	local := make([]int, len(wires))
+	for i, wire := range wires {
+		local[i] = m.siteOf[wire] - start
+	}
+	m.applyBlock(start, len(wires), gate, local)
+}
+
+// measures wire in the Z basis, choose picks the outcome given the chance of reading 1
+func (m *mps) measure(wire int, choose func(probabilityOne float64) (int, error)) (int, error) {
+	site := m.siteOf[wire]
+	m.moveCenter(site)
+
+	// at the center the whole norm sits in this one tensor
+	a := m.sites[site]
+	p := [2]float64{}
+	for l := 0; l < a.left; l++ {
+		for s := 0; s < 2; s++ {
+			for r := 0; r < a.right; r++ {
+				v := a.data[(l*2+s)*a.right+r]
+				p[s] += real(v)*real(v) + imag(v)*imag(v)
+			}
+		}
+	}
+	outcome, err := choose(p[1] / (p[0] + p[1]))
+	if err != nil {
+		return 0, err
+	}
+
+	scale := complex(1/math.Sqrt(p[outcome]), 0)
+	for l := 0; l < a.left; l++ {
+		for s := 0; s < 2; s++ {
+			for r := 0; r < a.right; r++ {
+				i := (l*2+s)*a.right + r
+				if s == outcome {
+					a.data[i] *= scale
+				} else {
+					a.data[i] = 0
+				}
+			}
+		}
+	}
+	return outcome, nil
+}
+
+// amplitude of a bitstring, wire 0 first
+func (m *mps) amplitude(key string) complex128 {
+	v := []complex128{1}
+	for i, site := range m.sites {
+		s := int(key[m.wireAt[i]] - '0')
+		next := make([]complex128, site.right)
+		for l, vl := range v {
+			for r := range next {
+				next[r] += vl * site.data[(l*2+s)*site.right+r]
+			}
+		}
+		v = next
+	}
+	return v[0]
+}
+
+// single-site Paulis, P[s][s']
+var pauliMatrices = map[byte][2][2]complex128{
+	'X': {{0, 1}, {1, 0}},
+	'Y': {{0, -1i}, {1i, 0}},
+	'Z': {{1, 0}, {0, -1}},
+}
+
+// ⟨ψ|P|ψ⟩ / ⟨ψ|ψ⟩, contracting the chain site by site with the Pauli placed between
+// the bra and ket of every site it acts on. wires past the chain are |0⟩
+func (m *mps) expectation(term PauliTerm) float64 {
+	onSite := make(map[int]byte)
+	for wire, pauli := range term.Paulis {
+		if wire >= len(m.sites) {
+			if pauli != 'Z' {
+				return 0
+			}
+			continue
+		}
+		onSite[m.siteOf[wire]] = pauli
+	}
+	value := m.transfer(onSite)
+	if norm := real(m.transfer(nil)); norm > 0 {
+		return real(value) / norm
+	}
+	return 0
+}
+
+// contracts ⟨ψ|P|ψ⟩ for the Paulis at the given sites, the environment E[l][l']
+// pairs the bra's left bond with the ket's. the canonical sites left of the center
+// and of the first Pauli contract to the identity, and those right of both to a trace,
+// so only the stretch in between is walked
+func (m *mps) transfer(onSite map[int]byte) complex128 {
+	first, last := m.center, m.center
+	for i := range onSite {
+		first, last = min(first, i), max(last, i)
+	}
+	env := make([][]complex128, m.sites[first].left)
+	for l := range env {
+		env[l] = make([]complex128, len(env))
+		env[l][l] = 1
+	}
+	for i := first; i <= last; i++ {
+		site := m.sites[i]
+		// the ket with the Pauli applied, B[l'][s][r'] = Σ_s' P[s][s'] A[l'][s'][r']
+		ket := site.data
+		if pauli, ok := onSite[i]; ok {
+			p := pauliMatrices[pauli]
+			ket = make([]complex128, len(site.data))
+			for l := 0; l < site.left; l++ {
+				for r := 0; r < site.right; r++ {
+					a0, a1 := site.data[(l*2)*site.right+r], site.data[(l*2+1)*site.right+r]
+					ket[(l*2)*site.right+r] = p[0][0]*a0 + p[0][1]*a1
+					ket[(l*2+1)*site.right+r] = p[1][0]*a0 + p[1][1]*a1
+				}
+			}
+		}
+
+		// half[l][s][r'] = Σ_l' E[l][l'] B[l'][s][r']
+		half := make([]complex128, len(site.data))
+		for l := 0; l < site.left; l++ {
+			for lk := 0; lk < site.left; lk++ {
+				e := env[l][lk]
+				if e == 0 {
+					continue
+				}
+				for j := 0; j < 2*site.right; j++ {
+					half[l*2*site.right+j] += e * ket[lk*2*site.right+j]
+				}
+			}
+		}
+
+		// E'[r][r'] = Σ_{l,s} conj(A[l][s][r]) half[l][s][r']
+		next := make([][]complex128, site.right)
+		for r := range next {
+			next[r] = make([]complex128, site.right)
+		}
+		for ls := 0; ls < 2*site.left; ls++ {
+			for r := 0; r < site.right; r++ {
+				bra := cmplx.Conj(site.data[ls*site.right+r])
+				if bra == 0 {
+					continue
+				}
+				for rk := 0; rk < site.right; rk++ {
+					next[r][rk] += bra * half[ls*site.right+rk]
+				}
+			}
+		}
+		env = next
+	}
+	trace := complex(0, 0)
+	for r := range env {
+		trace += env[r][r]
+	}
+	return trace
+}
+
+// entanglement entropy in bits across the bond after the first cut sites, from the
+// Schmidt values there. moving the center changes the tensors but not the state, so
+// it works on a copy
+func (m *mps) cutEntropy(cut int) float64 {
+	if cut <= 0 || cut >= len(m.sites) {
+		return 0
+	}
+	c := m.clone()
+	c.moveCenter(cut - 1)
+	site := c.sites[cut-1]
+	mat := make([][]complex128, site.left*2)
+	for i := range mat {
+		mat[i] = site.data[i*site.right : (i+1)*site.right]
+	}
+	_, s, _ := svd(mat)
+	total := 0.0
+	for _, v := range s {
+		total += v * v
+	}
+	entropy := 0.0
+	for _, v := range s {
+		if p := v * v / total; p > 1e-12 {
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return math.Max(0, entropy)
+}
+
+// the number of sites left of the cut when the wires fill one end of the chain
+func (m *mps) cutOf(wires []int) (int, bool) {
+	sites := make([]int, len(wires))
+	for i, wire := range wires {
+		sites[i] = m.siteOf[wire]
+	}
+	sort.Ints(sites)
+	if len(sites) == 0 || sites[len(sites)-1] == len(sites)-1 {
+		return len(sites), true
+	}
+	if sites[0] == len(m.sites)-len(sites) {
+		return sites[0], true
+	}
+	return 0, false
+}
+
+// samples bitstrings site by site, needs the center at site 0 so every later site
+// is right-canonical and the partial contractions give marginal probabilities
+func (m *mps) sample(shots int, rng *rand.Rand) map[string]int {
+	m.moveCenter(0)
+	counts := make(map[string]int)
+	key := make([]byte, len(m.sites))
+	for shot := 0; shot < shots; shot++ {
+		v := []complex128{1}
+		for i, site := range m.sites {
+			var branch [2][]complex128
+			var weight [2]float64
+			for s := 0; s < 2; s++ {
+				branch[s] = make([]complex128, site.right)
+				for l, vl := range v {
+					for r := range branch[s] {
+						branch[s][r] += vl * site.data[(l*2+s)*site.right+r]
+					}
+				}
+				for _, b := range branch[s] {
+					weight[s] += real(b)*real(b) + imag(b)*imag(b)
+				}
+			}
+			s := 0
+			if rng.Float64()*(weight[0]+weight[1]) >= weight[0] {
+				s = 1
+			}
+			key[m.wireAt[i]] = byte('0' + s)
+			v = branch[s]
+		}
+		counts[string(key)]++
+	}
+	return counts
+}
+
+// runs the circuit up to barrier n on a matrix product state
+func (c *Circuit) executeMPS(atBarrier int) (Result, error) {
+	numQubits := c.NumQubits()
+	bond := c.BondDimension
+	if bond == 0 {
+		bond = defaultBondDimension
+	}
+	if bond < 1 {
+		return Result{}, ErrInvalidBondDimension
+	}
+	for _, key := range c.Bitstrings {
+		if !validBitstring(key, numQubits) {
+			return Result{}, fmt.Errorf("%w: %q, want %d bits", ErrInvalidBitstring, key, numQubits)
+		}
+	}
+
+	state, classical, err := c.advance(BackendMPS, atBarrier, func() (checkpoint, error) {
+		m := newMPS(numQubits, bond)
+		m.prepare(c.Initial)
+		return mpsCheckpoint{m}, nil
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	m := state.(mpsCheckpoint).m
+	m.moveCenter(0)
+
+	// requested bitstrings, else everything for narrow circuits or the ones a few
+	// samples turn up for wide ones
+	keys := c.Bitstrings
+	if len(keys) == 0 && numQubits <= maxListedMPSWires {
+		for i := 0; i < 1<<numQubits; i++ {
+			keys = append(keys, strings.Join(intToBitString(i, numQubits), ""))
+		}
+	} else if len(keys) == 0 {
+		for key := range m.sample(listedMPSSamples, rand.New(rand.NewSource(c.Seed))) {
+			keys = append(keys, key)
+		}
+	}
+
+	amplitudes := make(map[string]complex128)
+	probabilities := make(map[string]float64)
+	for _, key := range keys {
+		amplitude := m.amplitude(key)
+		if p := real(amplitude)*real(amplitude) + imag(amplitude)*imag(amplitude); p > 1e-15 {
+			amplitudes[key] = amplitude
+			probabilities[key] = p
+		}
+	}
+
+	return Result{
+		StateVector:         amplitudes,
+		StateVectorSymbolic: SymbofyMap(amplitudes),
+		Probabilities:       probabilities,
+		ClassicalRegister:   classical,
+		TruncationError:     m.truncation,
+		MaxBondDimension:    m.widest,
+		sampler:             m,
+		expecter:            m,
+	}, nil
+}
+
+// runs gates from..to-1 of the circuit on the mps, measurement outcomes are written to classical
+func (c *Circuit) runMPSGates(m *mps, classical []int, from, to int) error {
+	for i := from; i < to; i++ {
+		gate := c.Gates[i]
+		if !gate.Condition.Holds(classical) {
+			continue
+		}
+		switch gate.Gate.(type) {
+		case MeasureGate, ResetGate:
+			outcome, err := m.measure(gate.Wires[0], func(probabilityOne float64) (int, error) {
+				return c.MeasurementOutcome(i, probabilityOne)
+			})
+			if err != nil {
+				return fmt.Errorf("gate %d: %w", i+1, err)
+			}
+			if _, ok := gate.Gate.(ResetGate); ok {
+				if outcome == 1 {
+					m.apply(PauliX().Data(), gate.Wires)
+				}
+				continue
+			}
+			classical[gate.Cbits[0]] = c.RecordedOutcome(i, gate.Wires[0], outcome)
+		case ChannelInterface:
+			return fmt.Errorf("gate %d: %w", i+1, ErrNoiseUnsupported)
+		default:
+			if len(c.Noise.after(gate)) > 0 {
+				return fmt.Errorf("gate %d: %w", i+1, ErrNoiseUnsupported)
+			}
+			data := gate.Gate.Data()
+			if data.Rows != data.Cols {
+				return ErrGateMatrixNotSquare
+			}
+			if data.Rows != 1<<len(gate.Wires) {
+				return ErrInvalidWireCount
+			}
+			m.apply(data, gate.Wires)
+		}
+	}
+	return nil
+}
+
+func validBitstring(key string, numQubits int) bool {
+	if len(key) != numQubits {
+		return false
+	}
+	for _, ch := range key {
+		if ch != '0' && ch != '1' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,198 @@
+package quantum
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestSVDReconstructs(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for _, shape := range [][2]int{{1, 1}, {4, 4}, {6, 3}, {3, 7}, {8, 8}} {
+		a := make([][]complex128, shape[0])
+		for i := range a {
+			a[i] = make([]complex128, shape[1])
+			for j := range a[i] {
+				a[i][j] = complex(rng.NormFloat64(), rng.NormFloat64())
+			}
+		}
+		// a rank deficient column
+		for i := range a {
+			a[i][0] = a[i][len(a[i])-1] * 2i
+		}
+
+		u, s, vh := svd(a)
+		for k := 1; k < len(s); k++ {
+			if s[k] > s[k-1] {
+				t.Fatalf("%v: singular values not sorted: %v", shape, s)
+			}
+		}
+		for i := range a {
+			for j := range a[i] {
+				sum := complex(0, 0)
+				for k := range s {
+					sum += u[i][k] * complex(s[k], 0) * vh[k][j]
+				}
+				if cmplx.Abs(sum-a[i][j]) > 1e-9 {
+					t.Fatalf("%v: U S Vh differs from A at %d,%d", shape, i, j)
+				}
+			}
+		}
+	}
+}
+
+func TestMPSMatchesStateVector(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	single := []string{"h", "x", "y", "t", "s", "rx", "ry"}
+	multi := []string{"cnot", "cz", "swap", "crx", "toff", "ccz"}
+	for trial := 0; trial < 20; trial++ {
+		numQubits := 3 + rng.Intn(4)
+		gates := randomCircuit(rng, numQubits, 25, single, multi)
+
+		circuit, err := NewCircuit(gates)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := circuit.ExecuteToBarrier(len(gates))
+		if err != nil {
+			t.Fatal(err)
+		}
+		circuit.Backend = BackendMPS
+		got, err := circuit.ExecuteToBarrier(len(gates))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.TruncationError > 1e-12 {
+			t.Errorf("%v: nothing should be truncated at the default bond, got %v", gates, got.TruncationError)
+		}
+		for key, amplitude := range want.StateVector {
+			if cmplx.Abs(got.StateVector[key]-amplitude) > 1e-9 {
+				t.Fatalf("%v: amplitude of %s is %v, state vector gives %v", gates, key, got.StateVector[key], amplitude)
+			}
+		}
+	}
+}
+
+func TestMPSWideGHZ(t *testing.T) {
+	const numQubits = 200
+	gates := []string{"h0"}
+	for i := 1; i < numQubits; i++ {
+		gates = append(gates, fmt.Sprintf("cnot%d,%d", i-1, i))
+	}
+	circuit, err := NewCircuit(gates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	circuit.Backend = BackendMPS
+	zeros, ones := strings.Repeat("0", numQubits), strings.Repeat("1", numQubits)
+	circuit.Bitstrings = []string{zeros, ones, strings.Repeat("01", numQubits/2)}
+
+	result, err := circuit.ExecuteToBarrier(len(gates))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(result.Probabilities[zeros]-0.5) > 1e-9 || math.Abs(result.Probabilities[ones]-0.5) > 1e-9 || len(result.Probabilities) != 2 {
+		t.Errorf("unexpected GHZ probabilities")
+	}
+	if result.MaxBondDimension != 2 {
+		t.Errorf("GHZ needs bond dimension 2, got %d", result.MaxBondDimension)
+	}
+
+	counts, err := circuit.Sample(200, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 2 || counts[zeros] < 70 || counts[ones] < 70 {
+		t.Errorf("unexpected GHZ counts over %d bitstrings", len(counts))
+	}
+}
+
+func TestMPSWideExpectation(t *testing.T) {
+	const numQubits = 60
+	gates := []string{"h0"}
+	for i := 1; i < numQubits; i++ {
+		gates = append(gates, fmt.Sprintf("cnot%d,%d", i-1, i))
+	}
+	gates = append(gates, "s5")
+	circuit, err := NewCircuit(gates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	circuit.Backend = BackendMPS
+
+	var xs []string
+	for i := 0; i < numQubits; i++ {
+		xs = append(xs, fmt.Sprintf("X%d", i))
+	}
+	// S on one wire of the GHZ state turns X...X into Y...X
+	ys := append([]string(nil), xs...)
+	ys[5] = "Y5"
+	for observable, want := range map[string]float64{
+		"Z0 Z59":                 1,
+		"Z3":                     0,
+		"X0":                     0,
+		strings.Join(ys, " "):    1,
+		strings.Join(xs, " "):    0,
+		"2*Z10 Z20 + Z70":        3,
+		"X70":                    0,
+		"-0.5*Z0 Z1 Z2 Z3 + Z58": -0.5,
+	} {
+		o, err := ParseObservable(observable)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := circuit.Expectation(o)
+		if err != nil {
+			t.Fatalf("⟨%s⟩: %v", observable, err)
+		}
+		if math.Abs(got-want) > 1e-9 {
+			t.Errorf("⟨%s⟩ = %v, want %v", observable, got, want)
+		}
+	}
+}
+
+func TestMPSTruncation(t *testing.T) {
+	// a bell pair cut to bond dimension 1 keeps one branch and loses half the weight
+	circuit, _ := NewCircuit(strings.Split("h0 cnot0,1", " "))
+	circuit.Backend = BackendMPS
+	circuit.BondDimension = 1
+	result, err := circuit.ExecuteToBarrier(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(result.TruncationError-0.5) > 1e-9 {
+		t.Errorf("truncation error = %v, want 0.5", result.TruncationError)
+	}
+	if len(result.Probabilities) != 1 {
+		t.Errorf("expected a single surviving branch, got %v", result.Probabilities)
+	}
+
+	circuit.BondDimension = -1
+	if _, err := circuit.ExecuteToBarrier(2); !errors.Is(err, ErrInvalidBondDimension) {
+		t.Errorf("expected ErrInvalidBondDimension, got %v", err)
+	}
+	circuit.BondDimension = 0
+	circuit.Bitstrings = []string{"012"}
+	if _, err := circuit.ExecuteToBarrier(2); !errors.Is(err, ErrInvalidBitstring) {
+		t.Errorf("expected ErrInvalidBitstring, got %v", err)
+	}
+}
+
+func TestMPSMeasureAndReset(t *testing.T) {
+	circuit, _ := NewCircuit(strings.Split("h0 cnot0,3 m3->0 x1?c0==1 reset0", " "))
+	circuit.Backend = BackendMPS
+	if err := circuit.SetOutcome(2, 1); err != nil {
+		t.Fatal(err)
+	}
+	result, err := circuit.ExecuteToBarrier(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(result.Probabilities["0101"]-1) > 1e-9 || result.ClassicalRegister[0] != 1 {
+		t.Errorf("expected 0101 after forcing c0=1, got %v", result.Probabilities)
+	}
+}
@@ -0,0 +1,206 @@
+package quantum
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strings"
+)
+
+// device noise description, loaded from JSON (YAML isn't supported):
+//
+//	{
+//	  "gate_errors": {"default": 0.001, "cnot": 0.01},
+//	  "gate_times":  {"default": 50, "cnot": 300},
+//	  "qubits": {
+//	    "0": {"t1": 50000, "t2": 70000, "readout": [[0.98, 0.02], [0.05, 0.95]]}
+//	  }
+//	}
+//
+// gate keys are the names or aliases used in circuit strings. after every gate a depolarizing
+// channel with the gate's error acts on its wires, on each one separately for gates on three
+// or more, followed by amplitude and phase damping for the gate's duration on each wire
+// with T1/T2 set. readout[actual][read] flips measured bits, both mid-circuit and in
+// sampled counts
+type NoiseModel struct {
+	// depolarizing probability per gate, "default" covers gates not listed
+	GateErrors map[string]float64 `json:"gate_errors"`
+	// gate durations in the same unit as T1/T2, "default" covers gates not listed
+	GateTimes map[string]float64 `json:"gate_times"`
+	// per wire relaxation times and readout error
+	Qubits map[int]QubitNoise `json:"qubits"`
+}
+
+type QubitNoise struct {
+	T1 float64 `json:"t1"`
+	T2 float64 `json:"t2"`
+	// Readout[actual][read] is the chance of reading read when the wire was actual
+	Readout [][]float64 `json:"readout"`
+}
+
+// channel the noise model applies to some wires
+type noiseOp struct {
+	channel ChannelInterface
+	wires   []int
+}
+
+// reads and validates a noise model file
+func LoadNoiseModel(path string) (*NoiseModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseNoiseModel(data)
+}
+
+// parses and validates a JSON noise model
+func ParseNoiseModel(data []byte) (*NoiseModel, error) {
+	model := &NoiseModel{}
+	if err := json.Unmarshal(data, model); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidNoiseModel, err)
+	}
+	if err := model.Validate(); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// checks probabilities, relaxation times and readout matrices
+func (m *NoiseModel) Validate() error {
+	for gate, p := range m.GateErrors {
+		if !validProbability(p) {
+			return fmt.Errorf("%w: gate error for %s must be in [0, 1]", ErrInvalidNoiseModel, gate)
+		}
+	}
+	for gate, t := range m.GateTimes {
+		if t < 0 {
+			return fmt.Errorf("%w: gate time for %s is negative", ErrInvalidNoiseModel, gate)
+		}
+	}
+	for wire, q := range m.Qubits {
+		if q.T1 < 0 || q.T2 < 0 {
+			return fmt.Errorf("%w: qubit %d has a negative T1/T2", ErrInvalidNoiseModel, wire)
+		}
+		if q.T1 > 0 && q.T2 > 2*q.T1 {
+			return fmt.Errorf("%w: qubit %d needs T2 <= 2*T1", ErrInvalidNoiseModel, wire)
+		}
+		if q.Readout == nil {
+			continue
+		}
+		if len(q.Readout) != 2 {
+			return fmt.Errorf("%w: qubit %d readout must be 2x2", ErrInvalidNoiseModel, wire)
+		}
+		for _, row := range q.Readout {
+			if len(row) != 2 || !validProbability(row[0]) || !validProbability(row[1]) || math.Abs(row[0]+row[1]-1) > 1e-9 {
+				return fmt.Errorf("%w: qubit %d readout rows must be probabilities summing to 1", ErrInvalidNoiseModel, wire)
+			}
+		}
+	}
+	return nil
+}
+
+// names a gate is written with in circuit strings, from the registry specs that build
+// its kind, e.g. "crx" for CRx(0.50) and "cnot" or "cx" for CNOT. t0' is a tdg, an
+// inverse without a name of its own goes by the gate it undoes and other gates the
+// registry doesn't build by their display name
+func gateKeys(gate GateInterface) []string {
+	if names, ok := gateNames[kindOf(gate)]; ok {
+		return names
+	}
+	if d, ok := gate.(DaggerGate); ok {
+		return gateKeys(d.inner)
+	}
+	name, _, _ := strings.Cut(gate.Name(), "(")
+	return []string{strings.ToLower(name)}
+}
+
+// value for the gate under any of its names, or the "default" entry
+func lookupGate(values map[string]float64, gate GateInterface) float64 {
+	for _, key := range gateKeys(gate) {
+		if v, ok := values[key]; ok {
+			return v
+		}
+	}
+	return values["default"]
+}
+
+// channels injected after a gate, nil for a nil model
+func (m *NoiseModel) after(gate CircuitGate) []noiseOp {
+	if m == nil {
+		return nil
+	}
+
+	var ops []noiseOp
+	if p := lookupGate(m.GateErrors, gate.Gate); p > 0 && len(gate.Wires) <= maxDepolarizedWires {
+		depol, _ := Depolarizing(p, len(gate.Wires))
+		ops = append(ops, noiseOp{channel: depol, wires: gate.Wires})
+	} else if p > 0 {
+		// the joint channel has 4^k Kraus operators, wider gates depolarize each wire
+		depol, _ := Depolarizing(p, 1)
+		for _, wire := range gate.Wires {
+			ops = append(ops, noiseOp{channel: depol, wires: []int{wire}})
+		}
+	}
+
+	t := lookupGate(m.GateTimes, gate.Gate)
+	if t <= 0 {
+		return ops
+	}
+	for _, wire := range gate.Wires {
+		q := m.Qubits[wire]
+		if q.T1 > 0 {
+			ad, _ := AmplitudeDamping(1 - math.Exp(-t/q.T1))
+			ops = append(ops, noiseOp{channel: ad, wires: []int{wire}})
+		}
+		// amplitude damping already shrinks coherences by exp(-t/2T1), dephasing adds the rest of T2
+		if q.T2 > 0 {
+			rate := 1 / q.T2
+			if q.T1 > 0 {
+				rate -= 1 / (2 * q.T1)
+			}
+			if rate > 0 {
+				pd, _ := PhaseDamping(1 - math.Exp(-2*t*rate))
+				ops = append(ops, noiseOp{channel: pd, wires: []int{wire}})
+			}
+		}
+	}
+	return ops
+}
+
+// bit read out for wire given its actual value
+func (m *NoiseModel) readout(wire, actual int, rng *rand.Rand) int {
+	if m == nil {
+		return actual
+	}
+	if rng.Float64() < m.readoutFlip(wire, actual) {
+		return 1 - actual
+	}
+	return actual
+}
+
+// chance that wire's readout flips the bit it actually holds
+func (m *NoiseModel) readoutFlip(wire, actual int) float64 {
+	if m == nil {
+		return 0
+	}
+	q, ok := m.Qubits[wire]
+	if !ok || q.Readout == nil {
+		return 0
+	}
+	return q.Readout[actual][1-actual]
+}
+
+// passes every bit of a sampled bitstring through its wire's readout error
+func (m *NoiseModel) readoutKey(key string, rng *rand.Rand) string {
+	if m == nil {
+		return key
+	}
+	bits := []byte(key)
+	for wire := range bits {
+		actual := int(bits[wire] - '0')
+		bits[wire] = byte('0' + m.readout(wire, actual, rng))
+	}
+	return string(bits)
+}
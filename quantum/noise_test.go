@@ -0,0 +1,161 @@
+package quantum
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestNoiseModelRelaxation(t *testing.T) {
+	model, err := ParseNoiseModel([]byte(`{
+		"gate_times": {"default": 10, "x": 100},
+		"qubits": {"0": {"t1": 200, "t2": 100}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	circuit, err := NewCircuit(strings.Split("x0 h0", " "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	circuit.Backend = BackendDensity
+	circuit.Noise = model
+
+	// after x0, |1⟩ has decayed for 100 time units
+	result, err := circuit.ExecuteToBarrier(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := math.Exp(-100.0 / 200); math.Abs(result.Probabilities["1"]-want) > 1e-9 {
+		t.Errorf("P(1) = %v, want %v", result.Probabilities["1"], want)
+	}
+
+	// coherence of |+⟩ decays as exp(-t/T2) over the 10 units of h0 (plus relaxation of the populations)
+	plus, _ := NewCircuit([]string{"h0"})
+	plus.Backend = BackendDensity
+	plus.Noise = model
+	result, err = plus.ExecuteToBarrier(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coherence := real(result.DensityMatrix.Data[0][1])
+	if want := 0.5 * math.Exp(-10.0/100); math.Abs(coherence-want) > 1e-9 {
+		t.Errorf("coherence = %v, want %v", coherence, want)
+	}
+}
+
+func TestNoiseModelReadout(t *testing.T) {
+	// wire 1 always reads 0
+	model, err := ParseNoiseModel([]byte(`{"qubits": {"1": {"readout": [[1, 0], [1, 0]]}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	circuit, err := NewCircuit(strings.Split("x0 x1 m1->0", " "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	circuit.Noise = model
+
+	result, err := circuit.ExecuteToBarrier(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ClassicalRegister[0] != 0 {
+		t.Errorf("readout error should record 0, got %v", result.ClassicalRegister)
+	}
+	counts, err := circuit.Sample(100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts["10"] != 100 {
+		t.Errorf("every shot should read 10, got %v", counts)
+	}
+}
+
+func TestNoiseModelValidation(t *testing.T) {
+	for _, bad := range []string{
+		`{"gate_errors": {"h": 1.5}}`,
+		`{"qubits": {"0": {"t1": 10, "t2": 30}}}`,
+		`{"qubits": {"0": {"readout": [[0.9, 0.2], [0, 1]]}}}`,
+		`not json`,
+	} {
+		if _, err := ParseNoiseModel([]byte(bad)); !errors.Is(err, ErrInvalidNoiseModel) {
+			t.Errorf("%s: expected ErrInvalidNoiseModel, got %v", bad, err)
+		}
+	}
+}
+
+func TestNoiseModelGateKeys(t *testing.T) {
+	// every gate is found under the names circuit strings use for it
+	tests := []struct {
+		gate string
+		keys []string
+	}{
+		{"gphase0(0.3)", []string{"gphase"}},
+		{"sx0", []string{"sx"}},
+		{"sxdg0", []string{"sxdg"}},
+		{"sdg0", []string{"sdg"}},
+		{"tdg0", []string{"tdg"}},
+		{"t0'", []string{"tdg"}},
+		{"inv(s0)", []string{"sdg"}},
+		{"inv(h0)", []string{"h"}},
+		{"rx0(0.4)'", []string{"rx"}},
+		{"p0(0.2)", []string{"p"}},
+		{"crx0,1(0.5)", []string{"crx"}},
+		{"cx0,1", []string{"cnot", "cx"}},
+		{"toff0,1,2", []string{"toff"}},
+		{"ccx0,1,2", []string{"ccx"}},
+		{"iswap0,1", []string{"iswap"}},
+		{"ampdamp0(0.1)", []string{"ampdamp"}},
+	}
+	for _, tt := range tests {
+		circuit, err := NewCircuit([]string{tt.gate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		gate := circuit.Gates[0].Gate
+		for _, key := range tt.keys {
+			if got := lookupGate(map[string]float64{"default": 1, key: 2}, gate); got != 2 {
+				t.Errorf("%s: %q entry not used, got %v", tt.gate, key, got)
+			}
+		}
+		if got := lookupGate(map[string]float64{"default": 1, "y": 2}, gate); got != 1 {
+			t.Errorf("%s: expected the default, got %v", tt.gate, got)
+		}
+	}
+}
+
+func TestNoiseModelDepolarizesWideGatesPerWire(t *testing.T) {
+	model := &NoiseModel{GateErrors: map[string]float64{"default": 0.1}}
+	circuit, err := NewCircuit([]string{"cnot0,1", "toff0,1,2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ops := model.after(circuit.Gates[0]); len(ops) != 1 || len(ops[0].channel.Kraus()) != 16 {
+		t.Errorf("cnot should get one two wire channel, got %d ops", len(ops))
+	}
+	ops := model.after(circuit.Gates[1])
+	if len(ops) != 3 {
+		t.Fatalf("toff should get a channel per wire, got %d ops", len(ops))
+	}
+	for _, op := range ops {
+		if len(op.wires) != 1 || len(op.channel.Kraus()) != 4 {
+			t.Errorf("expected a single wire depolarizing channel, got %d Kraus operators on %v", len(op.channel.Kraus()), op.wires)
+		}
+	}
+
+	// each wire of |000⟩ is left alone with probability 1 - p/2
+	toff, _ := NewCircuit([]string{"toff0,1,2"})
+	toff.Backend = BackendDensity
+	toff.Noise = model
+	result, err := toff.ExecuteToBarrier(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := math.Pow(0.95, 3); math.Abs(result.Probabilities["000"]-want) > 1e-9 {
+		t.Errorf("P(000) = %v, want %v", result.Probabilities["000"], want)
+	}
+}
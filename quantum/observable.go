@@ -0,0 +1,388 @@
+package quantum
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// observables are real weighted sums of Pauli strings like "Z0 Z1 + 0.5*X2 - Y0 Y3",
+// coefficients take the same arithmetic as gate arguments
+
+// one Pauli string with its weight
+type PauliTerm struct {
+	Coefficient float64
+	// 'X', 'Y' or 'Z' per wire, wires left out are identity
+	Paulis map[int]byte
+}
+
+// weighted sum of Pauli strings
+type Observable struct {
+	Terms []PauliTerm
+}
+
+// a single Pauli factor like Z0 or x12
+var pauliRegex = regexp.MustCompile(`^([ixyzIXYZ])(\d+)$`)
+
+// parses a sum of Pauli strings, each optionally led by a coefficient and a *
+func ParseObservable(s string) (Observable, error) {
+	bodies, signs, err := splitTerms(s)
+	if err != nil {
+		return Observable{}, err
+	}
+	observable := Observable{}
+	for i, body := range bodies {
+		term, err := parsePauliTerm(body)
+		if err != nil {
+			return Observable{}, err
+		}
+		term.Coefficient *= signs[i]
+		observable.Terms = append(observable.Terms, term)
+	}
+	return observable, nil
+}
+
+// splits at the + and - between terms, leaving signs inside parentheses and
+// leading coefficients alone
+func splitTerms(s string) ([]string, []float64, error) {
+	var bodies []string
+	var signs []float64
+	sign := 1.0
+	start, depth := 0, 0
+	// last non space rune, 0 at the start of a term
+	var prev rune
+	for i, r := range s {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth < 0 {
+				return nil, nil, fmt.Errorf("%w: unbalanced parentheses", ErrInvalidObservable)
+			}
+		case (r == '+' || r == '-') && depth == 0 && prev != 0 && !strings.ContainsRune("*/+-^(", prev):
+			bodies = append(bodies, s[start:i])
+			signs = append(signs, sign)
+			sign = 1
+			if r == '-' {
+				sign = -1
+			}
+			start = i + 1
+			prev = 0
+			continue
+		}
+		if !unicode.IsSpace(r) {
+			prev = r
+		}
+	}
+	if depth != 0 {
+		return nil, nil, fmt.Errorf("%w: unbalanced parentheses", ErrInvalidObservable)
+	}
+	bodies = append(bodies, s[start:])
+	signs = append(signs, sign)
+	return bodies, signs, nil
+}
+
+// parses one term: an optional coefficient then Pauli factors split by spaces or *
+func parsePauliTerm(body string) (PauliTerm, error) {
+	term := PauliTerm{Coefficient: 1, Paulis: make(map[int]byte)}
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return PauliTerm{}, fmt.Errorf("%w: empty term", ErrInvalidObservable)
+	}
+
+	// the Pauli factors start at the first token that is one, tokens only split
+	// outside parentheses
+	factors := -1
+	depth := 0
+	for i, r := range body {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		// a token starts the term, follows a separator or follows the term's sign
+		if depth != 0 || (i > 0 && !isFactorSeparator(rune(body[i-1])) && !(i == 1 && strings.ContainsRune("+-", rune(body[0])))) {
+			continue
+		}
+		token := body[i:]
+		if end := strings.IndexFunc(token, isFactorSeparator); end >= 0 {
+			token = token[:end]
+		}
+		if pauliRegex.MatchString(token) {
+			factors = i
+			break
+		}
+	}
+
+	coefficient := body
+	if factors >= 0 {
+		coefficient = body[:factors]
+		for _, token := range strings.FieldsFunc(body[factors:], isFactorSeparator) {
+			match := pauliRegex.FindStringSubmatch(token)
+			if match == nil {
+				return PauliTerm{}, fmt.Errorf("%w: %q is not a Pauli like Z0", ErrInvalidObservable, token)
+			}
+			wire, err := strconv.Atoi(match[2])
+			if err != nil || wire > maxParsedWire() {
+				return PauliTerm{}, fmt.Errorf("%w, max: %d", ErrTooManyWires, maxParsedWire())
+			}
+			if _, ok := term.Paulis[wire]; ok {
+				return PauliTerm{}, fmt.Errorf("%w: wire %d appears twice in %q", ErrInvalidObservable, wire, body)
+			}
+			if pauli := strings.ToUpper(match[1])[0]; pauli != 'I' {
+				term.Paulis[wire] = pauli
+			} else {
+				// identities still claim their wire
+				term.Paulis[wire] = 0
+			}
+		}
+		for wire, pauli := range term.Paulis {
+			if pauli == 0 {
+				delete(term.Paulis, wire)
+			}
+		}
+	}
+
+	coefficient = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(coefficient), "*"))
+	switch coefficient {
+	case "", "+":
+	case "-":
+		term.Coefficient = -1
+	default:
+		value, err := evaluateArgument(coefficient)
+		if err != nil {
+			return PauliTerm{}, fmt.Errorf("%w: bad coefficient %q", ErrInvalidObservable, coefficient)
+		}
+		term.Coefficient = value
+	}
+	return term, nil
+}
+
+func isFactorSeparator(r rune) bool {
+	return unicode.IsSpace(r) || r == '*'
+}
+
+// the observable written back as a sum, wires in order
+func (o Observable) String() string {
+	var sb strings.Builder
+	for i, term := range o.Terms {
+		coefficient := term.Coefficient
+		if i > 0 && coefficient < 0 {
+			sb.WriteString(" - ")
+			coefficient = -coefficient
+		} else if i > 0 {
+			sb.WriteString(" + ")
+		}
+		paulis := term.String()
+		switch {
+		case paulis == "":
+			sb.WriteString(strconv.FormatFloat(coefficient, 'g', -1, 64))
+		case coefficient == 1:
+		case coefficient == -1:
+			sb.WriteString("-")
+		default:
+			sb.WriteString(strconv.FormatFloat(coefficient, 'g', -1, 64) + "*")
+		}
+		sb.WriteString(paulis)
+	}
+	return sb.String()
+}
+
+// the Pauli factors of a term like X0 Z3, empty for the identity
+func (t PauliTerm) String() string {
+	wires := make([]int, 0, len(t.Paulis))
+	for wire := range t.Paulis {
+		wires = append(wires, wire)
+	}
+	sort.Ints(wires)
+	factors := make([]string, len(wires))
+	for i, wire := range wires {
+		factors[i] = fmt.Sprintf("%c%d", t.Paulis[wire], wire)
+	}
+	return strings.Join(factors, " ")
+}
+
+// whether the term only has Z factors, so it is read from probabilities alone
+func (t PauliTerm) diagonal() bool {
+	for _, pauli := range t.Paulis {
+		if pauli != 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// ⟨O⟩ at barrier n of the circuit on its backend
+func (c *Circuit) ExpectationToBarrier(o Observable, atBarrier int) (float64, error) {
+	result, err := c.ExecuteToBarrier(atBarrier)
+	if err != nil {
+		return 0, err
+	}
+	return result.Expectation(o)
+}
+
+// ⟨O⟩ at the end of the circuit
+func (c *Circuit) Expectation(o Observable) (float64, error) {
+	return c.ExpectationToBarrier(o, len(c.Gates))
+}
+
+// ⟨O⟩ of the result's state. it needs the density matrix, every amplitude or the
+// backend's own state; Z only observables also work from the probabilities
+func (r Result) Expectation(o Observable) (float64, error) {
+	total := 0.0
+	for _, term := range o.Terms {
+		value, err := r.termExpectation(term)
+		if err != nil {
+			return 0, err
+		}
+		total += term.Coefficient * value
+	}
+	return total, nil
+}
+
+// backends whose result can't list the whole state answer Pauli expectations themselves
+type pauliExpecter interface {
+	expectation(term PauliTerm) float64
+}
+
+// ⟨P⟩ of one Pauli string, wires past the state's are |0⟩
+func (r Result) termExpectation(term PauliTerm) (float64, error) {
+	if r.expecter != nil {
+		return r.expecter.expectation(term), nil
+	}
+
+	if r.DensityMatrix != nil {
+		numQubits := 0
+		for 1<<numQubits < r.DensityMatrix.Rows {
+			numQubits++
+		}
+		xMask, zMask, ys, ok := pauliMasks(term, numQubits)
+		if !ok {
+			return 0, nil
+		}
+		// P|i⟩ = i^ys (-1)^|i&zMask| |i^xMask⟩, so Tr(ρP) = Σ ρ[i][i^xMask] times that phase
+		sum := complex(0, 0)
+		for i := range r.DensityMatrix.Data {
+			sum += r.DensityMatrix.Data[i][i^xMask] * pauliPhase(i, zMask, ys)
+		}
+		return real(sum), nil
+	}
+
+	// listed amplitudes or probabilities only pin the state down when they cover all of it
+	if complete(r.StateVector) {
+		sum := complex(0, 0)
+		for key, amplitude := range r.StateVector {
+			flipped, phase, ok := applyPauliToKey(term, key)
+			if !ok {
+				return 0, nil
+			}
+			sum += cmplx.Conj(r.StateVector[flipped]) * phase * amplitude
+		}
+		return real(sum), nil
+	}
+	if term.diagonal() && completeProbabilities(r.Probabilities) {
+		sum := 0.0
+		for key, p := range r.Probabilities {
+			_, phase, _ := applyPauliToKey(term, key)
+			sum += real(phase) * p
+		}
+		return sum, nil
+	}
+	return 0, fmt.Errorf("%w: %s", ErrExpectationUnsupported, term)
+}
+
+// bit masks of the X/Y and Z/Y factors over numQubits wires and the number of Ys;
+// ok is false when an X or Y acts past the last wire, which zeroes the term
+func pauliMasks(term PauliTerm, numQubits int) (xMask, zMask, ys int, ok bool) {
+	for wire, pauli := range term.Paulis {
+		if wire >= numQubits {
+			if pauli != 'Z' {
+				return 0, 0, 0, false
+			}
+			continue
+		}
+		mask := 1 << (numQubits - 1 - wire)
+		if pauli == 'X' || pauli == 'Y' {
+			xMask |= mask
+		}
+		if pauli == 'Z' || pauli == 'Y' {
+			zMask |= mask
+		}
+		if pauli == 'Y' {
+			ys++
+		}
+	}
+	return xMask, zMask, ys, true
+}
+
+// i^ys (-1)^|i&zMask|, from Y = iXZ
+func pauliPhase(i, zMask, ys int) complex128 {
+	phase := []complex128{1, 1i, -1, -1i}[ys%4]
+	if parity(i&zMask) == 1 {
+		phase = -phase
+	}
+	return phase
+}
+
+func parity(v int) int {
+	p := 0
+	for ; v != 0; v &= v - 1 {
+		p ^= 1
+	}
+	return p
+}
+
+// P|key⟩ = phase |flipped⟩
+func applyPauliToKey(term PauliTerm, key string) (string, complex128, bool) {
+	flipped := []byte(key)
+	phase := complex(1, 0)
+	for wire, pauli := range term.Paulis {
+		if wire >= len(key) {
+			if pauli != 'Z' {
+				return "", 0, false
+			}
+			continue
+		}
+		one := key[wire] == '1'
+		if (pauli == 'Z' || pauli == 'Y') && one {
+			phase = -phase
+		}
+		if pauli == 'Y' {
+			phase *= 1i
+		}
+		if pauli == 'X' || pauli == 'Y' {
+			flipped[wire] ^= '0' ^ '1'
+		}
+	}
+	return string(flipped), phase, true
+}
+
+// whether the amplitudes' probabilities add up to the whole state
+func complete(amplitudes map[string]complex128) bool {
+	if len(amplitudes) == 0 {
+		return false
+	}
+	total := 0.0
+	for _, amplitude := range amplitudes {
+		total += real(amplitude)*real(amplitude) + imag(amplitude)*imag(amplitude)
+	}
+	return math.Abs(total-1) < 1e-9
+}
+
+func completeProbabilities(probabilities map[string]float64) bool {
+	if len(probabilities) == 0 {
+		return false
+	}
+	total := 0.0
+	for _, p := range probabilities {
+		total += p
+	}
+	return math.Abs(total-1) < 1e-9
+}
@@ -0,0 +1,155 @@
+package quantum
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestParseObservable(t *testing.T) {
+	tests := []struct {
+		input        string
+		coefficients []float64
+		paulis       []string
+	}{
+		{"Z0 Z1 + 0.5*X2 - Y0 Y3", []float64{1, 0.5, -1}, []string{"Z0 Z1", "X2", "Y0 Y3"}},
+		{"-pi/2*x0*y1", []float64{-math.Pi / 2}, []string{"X0 Y1"}},
+		{"2*(1-3) Z0 I1", []float64{-4}, []string{"Z0"}},
+		{"0.001*Z0 + 1", []float64{0.001, 1}, []string{"Z0", ""}},
+		{"- X0 -2*2 * Z1", []float64{-1, -4}, []string{"X0", "Z1"}},
+		{"-Y1 + (-0.5)*X0", []float64{-1, -0.5}, []string{"Y1", "X0"}},
+	}
+	for _, tt := range tests {
+		o, err := ParseObservable(tt.input)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.input, err)
+		}
+		if len(o.Terms) != len(tt.coefficients) {
+			t.Fatalf("%q: got %d terms, want %d", tt.input, len(o.Terms), len(tt.coefficients))
+		}
+		for i, term := range o.Terms {
+			if math.Abs(term.Coefficient-tt.coefficients[i]) > 1e-12 || term.String() != tt.paulis[i] {
+				t.Errorf("%q: term %d is %v*%q, want %v*%q", tt.input, i, term.Coefficient, term.String(), tt.coefficients[i], tt.paulis[i])
+			}
+		}
+
+		// the written form parses back to the same terms
+		again, err := ParseObservable(o.String())
+		if err != nil || again.String() != o.String() {
+			t.Errorf("%q: %q doesn't round trip: %v", tt.input, o.String(), err)
+		}
+	}
+
+	for _, input := range []string{"", "Z0 Z0", "0.5*Q1", "Z0 +", "(Z0", "Z0 2"} {
+		if _, err := ParseObservable(input); !errors.Is(err, ErrInvalidObservable) {
+			t.Errorf("%q: expected ErrInvalidObservable, got %v", input, err)
+		}
+	}
+}
+
+func TestExpectationOfBellPair(t *testing.T) {
+	want := map[string]float64{"Z0 Z1": 1, "X0 X1": 1, "Y0 Y1": -1, "Z0": 0, "X0": 0, "Z0 Z1 - 0.5*Y0 Y1 + 2": 3.5, "Z5": 1, "X5": 0}
+	for _, backend := range []string{BackendStateVector, BackendDensity, BackendStabilizer, BackendMPS} {
+		c, err := NewCircuit([]string{"h0", "cnot0,1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Backend = backend
+		for input, expected := range want {
+			o, err := ParseObservable(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := c.Expectation(o)
+			if err != nil {
+				t.Fatalf("%s: %s: %v", backend, input, err)
+			}
+			if math.Abs(got-expected) > 1e-9 {
+				t.Errorf("%s: ⟨%s⟩ = %v, want %v", backend, input, got, expected)
+			}
+		}
+	}
+}
+
+func TestExpectationFromProbabilities(t *testing.T) {
+	c, _ := NewCircuit([]string{"x0", "h1", "cnot1,2"})
+	c.Backend = BackendTrajectory
+	c.Trajectories = 2
+
+	// Z strings only need the probabilities
+	o, _ := ParseObservable("Z0 + Z1 Z2")
+	got, err := c.Expectation(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got-0) > 1e-9 {
+		t.Errorf("⟨Z0 + Z1 Z2⟩ = %v, want 0", got)
+	}
+
+	o, _ = ParseObservable("X1 X2")
+	if _, err := c.Expectation(o); !errors.Is(err, ErrExpectationUnsupported) {
+		t.Errorf("expected ErrExpectationUnsupported, got %v", err)
+	}
+}
+
+func randomPauliString(rng *rand.Rand, numQubits int) string {
+	var factors []string
+	for wire := 0; wire < numQubits; wire++ {
+		if pauli := "IXYZ"[rng.Intn(4)]; pauli != 'I' {
+			factors = append(factors, fmt.Sprintf("%c%d", pauli, wire))
+		}
+	}
+	if len(factors) == 0 {
+		return "1"
+	}
+	return strings.Join(factors, " ")
+}
+
+func TestExpectationMatchesAcrossBackends(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	single := []string{"h", "x", "y", "z", "s"}
+	double := []string{"cnot", "cz", "swap"}
+	for trial := 0; trial < 30; trial++ {
+		numQubits := 2 + rng.Intn(3)
+		gates := randomCircuit(rng, numQubits, 20, single, double)
+		clifford := trial%2 == 0
+		if !clifford {
+			gates = append(gates, fmt.Sprintf("ry%d(0.3)", rng.Intn(numQubits)), fmt.Sprintf("t%d", rng.Intn(numQubits)))
+		}
+
+		var terms []string
+		for i := 0; i < 4; i++ {
+			terms = append(terms, fmt.Sprintf("%d*%s", i+1, randomPauliString(rng, numQubits)))
+		}
+		o, err := ParseObservable(strings.Join(terms, " + "))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c, err := NewCircuit(gates)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := c.Expectation(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		backends := []string{BackendDensity, BackendMPS}
+		if clifford {
+			backends = append(backends, BackendStabilizer)
+		}
+		for _, backend := range backends {
+			c.Backend = backend
+			got, err := c.Expectation(o)
+			if err != nil {
+				t.Fatalf("%s: %v", backend, err)
+			}
+			if math.Abs(got-want) > 1e-9 {
+				t.Errorf("%s: ⟨%s⟩ after %v = %v, statevector has %v", backend, o, gates, got, want)
+			}
+		}
+	}
+}
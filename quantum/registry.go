@@ -0,0 +1,281 @@
+package quantum
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// gate registry
+//
+// every gate a circuit string can name is described once: how it's spelled, the wires
+// and arguments it takes, how to build it and how Draw renders it. the parser, gate
+// listings and Draw all read from here, and RegisterGate adds to it
+
+// how Draw renders a gate
+type DrawStyle int
+
+const (
+	// the name on every wire the gate acts on
+	DrawBox DrawStyle = iota
+	// a dot on every wire but the last, which gets the name
+	DrawControlled
+	// the name in wavy padding on every wire, for noise
+	DrawNoise
+)
+
+// a gate as circuit strings spell it
+type GateSpec struct {
+	// lowercase name in circuit strings, e.g. "crx" for crx0,1(pi/2)
+	Name string
+	// other names circuit strings may use for it
+	Aliases []string
+	// wires it acts on, 0 for any number of at least one
+	Wires int
+	// comma separated arguments in parentheses after the wires
+	Params int
+	// arguments used when a circuit string leaves them out, all 0 when nil
+	Defaults []float64
+	// builds the gate from its evaluated arguments and the number of wires it was given
+	New   func(params []float64, wires int) (GateInterface, error)
+	Style DrawStyle
+}
+
+// a gate's type and full name, which tell the kinds of gate apart even when
+// several share a type
+type gateKind struct {
+	t        reflect.Type
+	fullName string
+}
+
+var (
+	// registered specs in registration order
+	gateSpecs []*GateSpec
+	// specs by name and alias
+	gateSpecsByName = map[string]*GateSpec{}
+	// draw style of each kind of gate the registry builds
+	gateStyles = map[gateKind]DrawStyle{}
+	// names and aliases of the specs building each kind of gate, in registration order
+	gateNames = map[gateKind][]string{}
+)
+
+func fixedGate(build func() GateInterface) func([]float64, int) (GateInterface, error) {
+	return func([]float64, int) (GateInterface, error) {
+		return build(), nil
+	}
+}
+
+func rotationGate(build func(theta float64) GateInterface) func([]float64, int) (GateInterface, error) {
+	return func(params []float64, _ int) (GateInterface, error) {
+		return build(params[0]), nil
+	}
+}
+
+func probabilityChannel(build func(p float64) (ChannelInterface, error)) func([]float64, int) (GateInterface, error) {
+	return func(params []float64, _ int) (GateInterface, error) {
+		return build(params[0])
+	}
+}
+
+// S† and T† under their own names, as sdg0 and tdg0
+func namedDagger(g GateInterface, example string) func([]float64, int) (GateInterface, error) {
+	return func([]float64, int) (GateInterface, error) {
+		dagger, err := Dagger(g)
+		if err != nil {
+			return nil, err
+		}
+		d := dagger.(DaggerGate)
+		d.example = example
+		return d, nil
+	}
+}
+
+// the built-in gates, in the order gate listings show them
+var builtinGates = []GateSpec{
+	{Name: "i", Wires: 1, New: fixedGate(func() GateInterface { return Identity(2) })},
+	{Name: "h", Wires: 1, New: fixedGate(Hadamard)},
+	{Name: "x", Wires: 1, New: fixedGate(PauliX)},
+	{Name: "y", Wires: 1, New: fixedGate(PauliY)},
+	{Name: "z", Wires: 1, New: fixedGate(PauliZ)},
+	{Name: "cnot", Aliases: []string{"cx"}, Wires: 2, New: fixedGate(CNOT), Style: DrawControlled},
+	{Name: "cz", Wires: 2, New: fixedGate(CZ), Style: DrawControlled},
+	{Name: "cy", Wires: 2, New: fixedGate(CY), Style: DrawControlled},
+	{Name: "ch", Wires: 2, New: fixedGate(CH), Style: DrawControlled},
+	{Name: "swap", Wires: 2, New: fixedGate(SWAP)},
+	{Name: "iswap", Wires: 2, New: fixedGate(ISWAP)},
+	{Name: "ecr", Wires: 2, New: fixedGate(ECR)},
+	{Name: "toff", Wires: 3, New: fixedGate(Toffoli), Style: DrawControlled},
+	{Name: "cswap", Aliases: []string{"fredkin"}, Wires: 3, New: fixedGate(Fredkin), Style: DrawControlled},
+	{Name: "t", Wires: 1, New: fixedGate(T)},
+	{Name: "tdg", Wires: 1, New: namedDagger(T(), "tdg0")},
+	{Name: "s", Wires: 1, New: fixedGate(S)},
+	{Name: "sdg", Wires: 1, New: namedDagger(S(), "sdg0")},
+	{Name: "sx", Wires: 1, New: fixedGate(SX)},
+	{Name: "sxdg", Wires: 1, New: namedDagger(SX(), "sxdg0")},
+	// p0 was S before it took an angle, and still is
+	{Name: "p", Wires: 1, Params: 1, Defaults: []float64{math.Pi / 2}, New: rotationGate(PhaseShift)},
+	{Name: "cp", Wires: 2, Params: 1, New: rotationGate(CPhase), Style: DrawControlled},
+	{Name: "gphase", Wires: 1, Params: 1, New: rotationGate(GlobalPhase)},
+	{Name: "rx", Wires: 1, Params: 1, New: rotationGate(Rx)},
+	{Name: "ry", Wires: 1, Params: 1, New: rotationGate(Ry)},
+	{Name: "rz", Wires: 1, Params: 1, New: rotationGate(Rz)},
+	{Name: "crx", Wires: 2, Params: 1, New: rotationGate(CRx), Style: DrawControlled},
+	{Name: "cry", Wires: 2, Params: 1, New: rotationGate(CRy), Style: DrawControlled},
+	{Name: "crz", Wires: 2, Params: 1, New: rotationGate(CRz), Style: DrawControlled},
+	{Name: "rxx", Wires: 2, Params: 1, New: rotationGate(RXX)},
+	{Name: "ryy", Wires: 2, Params: 1, New: rotationGate(RYY)},
+	{Name: "rzz", Wires: 2, Params: 1, New: rotationGate(RZZ)},
+	{Name: "u", Wires: 1, Params: 3, New: func(params []float64, _ int) (GateInterface, error) {
+		return U(params[0], params[1], params[2]), nil
+	}},
+	{Name: "cu", Wires: 2, Params: 3, New: func(params []float64, _ int) (GateInterface, error) {
+		return CU(params[0], params[1], params[2]), nil
+	}, Style: DrawControlled},
+	{Name: "ccx", Wires: 3, New: fixedGate(CCX), Style: DrawControlled},
+	{Name: "ccz", Wires: 3, New: fixedGate(CCZ), Style: DrawControlled},
+	// written m0->0, the parser adds the classical bit
+	{Name: "m", Wires: 1, New: fixedGate(Measure)},
+	{Name: "reset", Wires: 1, New: fixedGate(Reset)},
+	{Name: "depol", Params: 1, New: func(params []float64, wires int) (GateInterface, error) {
+		return Depolarizing(params[0], wires)
+	}, Style: DrawNoise},
+	{Name: "ampdamp", Wires: 1, Params: 1, New: probabilityChannel(AmplitudeDamping), Style: DrawNoise},
+	{Name: "phasedamp", Wires: 1, Params: 1, New: probabilityChannel(PhaseDamping), Style: DrawNoise},
+	{Name: "bitflip", Wires: 1, Params: 1, New: probabilityChannel(BitFlip), Style: DrawNoise},
+}
+
+func init() {
+	for _, spec := range builtinGates {
+		if err := RegisterGate(spec); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// makes a gate usable in circuit strings as <name><wires>(<args>), call it from init
+// so every circuit sees the same gates
+func RegisterGate(spec GateSpec) error {
+	if spec.New == nil || spec.Wires < 0 || spec.Params < 0 {
+		return fmt.Errorf("%w: gate %q needs a constructor and non-negative wire and argument counts", ErrInvalidArgument, spec.Name)
+	}
+	if spec.Defaults != nil && len(spec.Defaults) != spec.Params {
+		return fmt.Errorf("%w: gate %q has %d default(s) for %d argument(s)", ErrInvalidArgument, spec.Name, len(spec.Defaults), spec.Params)
+	}
+	names := append([]string{spec.Name}, spec.Aliases...)
+	for _, name := range names {
+		// inv(...), c(...), ctrl@ and negctrl@ are modifiers
+		if !gateNameRegex.MatchString(name) || name == "inv" || name == "c" || name == "ctrl" || name == "negctrl" {
+			return fmt.Errorf("%w: gate name %q", ErrInvalidArgument, name)
+		}
+		if _, ok := gateSpecsByName[name]; ok {
+			return fmt.Errorf("%w: %s", ErrGateExists, name)
+		}
+	}
+
+	registered := spec
+	registered.Aliases = append([]string(nil), spec.Aliases...)
+	registered.Defaults = append([]float64(nil), spec.Defaults...)
+	gateSpecs = append(gateSpecs, &registered)
+	for _, name := range names {
+		gateSpecsByName[name] = &registered
+	}
+	// remember how this kind of gate draws, gates that can't be built from their default
+	// arguments fall back to the default style
+	if gate, err := registered.Sample(); err == nil {
+		if _, ok := gateStyles[kindOf(gate)]; !ok {
+			gateStyles[kindOf(gate)] = registered.Style
+		}
+		gateNames[kindOf(gate)] = append(gateNames[kindOf(gate)], names...)
+	}
+	return nil
+}
+
+// forgets the gate registered under name along with its aliases, so tests can register
+// theirs again on every run
+func unregisterGate(name string) {
+	spec, ok := gateSpecsByName[name]
+	if !ok {
+		return
+	}
+	for i, s := range gateSpecs {
+		if s == spec {
+			gateSpecs = append(gateSpecs[:i], gateSpecs[i+1:]...)
+			break
+		}
+	}
+	for _, n := range append([]string{spec.Name}, spec.Aliases...) {
+		delete(gateSpecsByName, n)
+		delete(customChannels, n)
+	}
+	// the style goes too unless another spec builds the same kind of gate
+	if gate, err := spec.Sample(); err == nil {
+		var names []string
+		for _, n := range gateNames[kindOf(gate)] {
+			if gateSpecsByName[n] != nil {
+				names = append(names, n)
+			}
+		}
+		gateNames[kindOf(gate)] = names
+		if len(names) == 0 {
+			delete(gateNames, kindOf(gate))
+		}
+		for _, s := range gateSpecs {
+			if other, err := s.Sample(); err == nil && kindOf(other) == kindOf(gate) {
+				return
+			}
+		}
+		delete(gateStyles, kindOf(gate))
+	}
+}
+
+// spec registered under a name or alias
+func LookupGate(name string) (GateSpec, bool) {
+	spec, ok := gateSpecsByName[name]
+	if !ok {
+		return GateSpec{}, false
+	}
+	return *spec, true
+}
+
+// every registered gate in registration order, built-ins first
+func GateSpecs() []GateSpec {
+	specs := make([]GateSpec, len(gateSpecs))
+	for i, spec := range gateSpecs {
+		specs[i] = *spec
+	}
+	return specs
+}
+
+// the gate built from its default arguments on as many wires as it needs, for listings
+func (s GateSpec) Sample() (GateInterface, error) {
+	return s.New(s.arguments(), max(s.Wires, 1))
+}
+
+// the arguments a circuit string that leaves them out gets
+func (s GateSpec) arguments() []float64 {
+	params := make([]float64, s.Params)
+	copy(params, s.Defaults)
+	return params
+}
+
+func kindOf(gate GateInterface) gateKind {
+	return gateKind{reflect.TypeOf(gate), gate.FullName()}
+}
+
+// how Draw renders a gate, from the spec that builds its kind. gates the registry
+// doesn't know are boxed, or drawn as noise when they're channels
+func drawStyleOf(gate GateInterface) DrawStyle {
+	switch g := gate.(type) {
+	case DaggerGate:
+		return drawStyleOf(g.inner)
+	case ControlledGate:
+		return DrawControlled
+	}
+	if style, ok := gateStyles[kindOf(gate)]; ok {
+		return style
+	}
+	if _, ok := gate.(ChannelInterface); ok {
+		return DrawNoise
+	}
+	return DrawBox
+}
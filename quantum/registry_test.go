@@ -0,0 +1,142 @@
+package quantum
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestRegisteredGatesParseFromTheirExamples(t *testing.T) {
+	for _, spec := range GateSpecs() {
+		sample, err := spec.Sample()
+		if err != nil {
+			t.Fatalf("%s: %v", spec.Name, err)
+		}
+		c, err := NewCircuit([]string{sample.Example()})
+		if err != nil {
+			t.Fatalf("%s: example %q doesn't parse: %v", spec.Name, sample.Example(), err)
+		}
+		if got := c.Gates[0].Gate; kindOf(got) != kindOf(sample) {
+			t.Errorf("%s: example %q parsed to %s", spec.Name, sample.Example(), got.FullName())
+		}
+		if drawStyleOf(sample) != spec.Style {
+			t.Errorf("%s: draws as %v, spec says %v", spec.Name, drawStyleOf(sample), spec.Style)
+		}
+	}
+
+	c, err := NewCircuit([]string{"cx0,1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Gates[0].Gate.(CNOTGate); !ok {
+		t.Errorf("cx parsed to %s", c.Gates[0].Gate.FullName())
+	}
+
+	for input, want := range map[string]error{
+		"h0(1)":    ErrInvalidArgument,
+		"m0":       ErrInvalidWireFormat,
+		"nope0":    ErrUnknownGate,
+		"depol(1)": nil,
+	} {
+		_, err := NewCircuit([]string{input})
+		if err == nil {
+			t.Errorf("%q: expected an error", input)
+		} else if want != nil && !errors.Is(err, want) {
+			t.Errorf("%q: expected %v, got %v", input, want, err)
+		}
+	}
+}
+
+// controlled √X, registered the way a library user would
+type csxGate struct {
+	Gate
+}
+
+func (g csxGate) WiresNeeded() int {
+	return 2
+}
+
+func (g csxGate) Example() string {
+	return "csx0,1"
+}
+
+func (g csxGate) FullName() string {
+	return "Controlled-Sqrt-X"
+}
+
+func TestRegisterGate(t *testing.T) {
+	half := complex(0.5, 0.5)
+	halfConj := complex(0.5, -0.5)
+	spec := GateSpec{
+		Name:    "csx",
+		Aliases: []string{"csqrtx"},
+		Wires:   2,
+		New: func([]float64, int) (GateInterface, error) {
+			return csxGate{Gate{Matrix: Matrix{Rows: 4, Cols: 4, Data: [][]complex128{
+				{1, 0, 0, 0},
+				{0, 1, 0, 0},
+				{0, 0, half, halfConj},
+				{0, 0, halfConj, half},
+			}}, name: "SX"}}, nil
+		},
+		Style: DrawControlled,
+	}
+	if err := RegisterGate(spec); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { unregisterGate("csx") })
+	if err := RegisterGate(spec); !errors.Is(err, ErrGateExists) {
+		t.Errorf("expected ErrGateExists, got %v", err)
+	}
+	if err := RegisterGate(GateSpec{Name: "h", Wires: 1, New: spec.New}); !errors.Is(err, ErrGateExists) {
+		t.Errorf("expected ErrGateExists for a built-in name, got %v", err)
+	}
+	if err := RegisterGate(GateSpec{Name: "Bad1", Wires: 1, New: spec.New}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument, got %v", err)
+	}
+
+	// two √X make an X, so the target flips when the control is set
+	c, err := NewCircuit([]string{"x0", "csx0,1", "csqrtx0,1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := c.ExecuteToBarrier(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmplx.Abs(result.StateVector["11"]) < 1-1e-12 || math.Abs(result.Probabilities["11"]-1) > 1e-12 {
+		t.Errorf("expected |11⟩, got %v", result.StateVector)
+	}
+	if drawStyleOf(c.Gates[1].Gate) != DrawControlled {
+		t.Errorf("registered gate doesn't draw with its spec's style")
+	}
+	if _, ok := LookupGate("csqrtx"); !ok {
+		t.Errorf("alias not found")
+	}
+	if _, err := NewCircuit([]string{"csx0"}); err == nil {
+		t.Errorf("expected a wire count error")
+	}
+}
+
+func TestUnregisterGate(t *testing.T) {
+	spec := GateSpec{Name: "tmpgate", Aliases: []string{"tmpalias"}, Wires: 1, New: fixedGate(Hadamard), Style: DrawControlled}
+	for run := 0; run < 2; run++ {
+		if err := RegisterGate(spec); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+		unregisterGate("tmpgate")
+	}
+	if _, ok := LookupGate("tmpalias"); ok {
+		t.Error("alias still registered")
+	}
+	for _, s := range GateSpecs() {
+		if s.Name == "tmpgate" {
+			t.Error("spec still listed")
+		}
+	}
+	// h builds the same kind of gate, so its style stays
+	if drawStyleOf(Hadamard()) != DrawBox {
+		t.Error("unregistering changed how h draws")
+	}
+}
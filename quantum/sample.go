@@ -0,0 +1,113 @@
+package quantum
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// runs the whole circuit and measures every wire shots times, seed makes it reproducible.
+// forced Outcomes hold in every shot, so the counts are conditioned on them, and the
+// other mid-circuit measurements roll afresh per shot
+func (c *Circuit) Sample(shots int, seed int64) (map[string]int, error) {
+	return c.SampleToBarrier(len(c.Gates), shots, seed)
+}
+
+// like Sample but stops at barrier n
+func (c *Circuit) SampleToBarrier(atBarrier, shots int, seed int64) (map[string]int, error) {
+	if shots < 1 {
+		return nil, ErrInvalidShots
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	backend, err := LookupBackend(c.Backend)
+	if err != nil {
+		return nil, err
+	}
+
+	// without mid-circuit measurements every shot sees the same final state, and
+	// some backends already average over every measurement branch
+	if !c.HasMeasurements() || backend.Capabilities().AveragesMeasurements {
+		result, err := c.ExecuteToBarrier(atBarrier)
+		if err != nil {
+			return nil, err
+		}
+		return c.withReadoutError(result.Sample(shots, rng), rng), nil
+	}
+
+	// otherwise each shot rolls its own measurement branch. a reseeded shot would wipe
+	// and refill the circuit's snapshots, so shots run without them
+	counts := make(map[string]int)
+	shot := *c
+	shot.snapshots = nil
+	for i := 0; i < shots; i++ {
+		shot.Seed = rng.Int63()
+		result, err := shot.ExecuteToBarrier(atBarrier)
+		if err != nil {
+			return nil, err
+		}
+		for key, n := range result.Sample(1, rng) {
+			counts[key] += n
+		}
+	}
+	return c.withReadoutError(counts, rng), nil
+}
+
+// passes every shot through the noise model's readout error
+func (c *Circuit) withReadoutError(counts map[string]int, rng *rand.Rand) map[string]int {
+	if c.Noise == nil {
+		return counts
+	}
+
+	// walk keys in a fixed order so the same rng always flips the same shots
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	noisy := make(map[string]int)
+	for _, key := range keys {
+		for i := 0; i < counts[key]; i++ {
+			noisy[c.Noise.readoutKey(key, rng)]++
+		}
+	}
+	return noisy
+}
+
+// draws shots bitstrings from the result's probabilities
+func (r Result) Sample(shots int, rng *rand.Rand) map[string]int {
+	if r.sampler != nil {
+		return r.sampler.sample(shots, rng)
+	}
+
+	// fixed key order so the same rng always gives the same counts
+	keys := make([]string, 0, len(r.Probabilities))
+	for key, p := range r.Probabilities {
+		if p > 0 {
+			keys = append(keys, key)
+		}
+	}
+	// equal length keys, so this is binary order
+	sort.Strings(keys)
+
+	cumulative := make([]float64, len(keys))
+	total := 0.0
+	for i, key := range keys {
+		total += r.Probabilities[key]
+		cumulative[i] = total
+	}
+
+	counts := make(map[string]int)
+	if len(keys) == 0 {
+		return counts
+	}
+	for i := 0; i < shots; i++ {
+		x := rng.Float64() * total
+		k := sort.SearchFloat64s(cumulative, x)
+		if k >= len(keys) {
+			k = len(keys) - 1
+		}
+		counts[keys[k]]++
+	}
+	return counts
+}
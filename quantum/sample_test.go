@@ -0,0 +1,66 @@
+package quantum
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSampleBellPair(t *testing.T) {
+	circuit, err := NewCircuit(strings.Split("h0 cnot0,1", " "))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts, err := circuit.Sample(1000, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts["00"]+counts["11"] != 1000 {
+		t.Errorf("bell pair should only give 00 and 11, got %v", counts)
+	}
+	if counts["00"] < 400 || counts["11"] < 400 {
+		t.Errorf("bell pair counts should be roughly even, got %v", counts)
+	}
+
+	again, _ := circuit.Sample(1000, 7)
+	if !reflect.DeepEqual(counts, again) {
+		t.Errorf("same seed should give the same counts, got %v and %v", counts, again)
+	}
+
+	if _, err := circuit.Sample(0, 7); err != ErrInvalidShots {
+		t.Errorf("expected ErrInvalidShots, got %v", err)
+	}
+}
+
+func TestSampleKeepsForcedOutcomes(t *testing.T) {
+	circuit, err := NewCircuit(strings.Split("h0 m0->0 x1?c0==1 h2", " "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	circuit.SetSnapshotBudget(1 << 20)
+	if err := circuit.SetOutcome(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := circuit.ExecuteToBarrier(4); err != nil {
+		t.Fatal(err)
+	}
+	bytes, snapshots := circuit.SnapshotBytes(), len(circuit.snapshots.snapshots)
+
+	// every shot reads the forced 1 on wire 0, so wire 1 always flips
+	counts, err := circuit.Sample(200, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts["110"]+counts["111"] != 200 || counts["110"] == 0 || counts["111"] == 0 {
+		t.Errorf("expected only 110 and 111, got %v", counts)
+	}
+
+	// the shots leave the circuit's own snapshots alone
+	if circuit.SnapshotBytes() != bytes || len(circuit.snapshots.snapshots) != snapshots {
+		t.Errorf("sampling changed the snapshots from %d to %d bytes", bytes, circuit.SnapshotBytes())
+	}
+	if circuit.snapshots.key.seed != circuit.Seed {
+		t.Errorf("snapshots were retaken for a shot's seed")
+	}
+}
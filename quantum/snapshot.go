@@ -0,0 +1,310 @@
+package quantum
+
+import "math"
+
+// per-barrier snapshots
+//
+// stepping through a circuit reruns every gate before the barrier on each step. with
+// a snapshot budget set, backends keep copies of their state at some barriers and
+// resume from the nearest one at or before the barrier asked for, so moving a step
+// forward costs one gate and moving back costs a copy. snapshots are spread over the
+// circuit so all of them fit the budget, the least recently used go first when not
+
+// a backend's state part way through the circuit
+type checkpoint interface {
+	// runs gates from..to-1 of the circuit on the state in place
+	run(c *Circuit, classical []int, from, to int) error
+	clone() checkpoint
+	// rough memory held, in bytes
+	size() int
+}
+
+type snapshot struct {
+	state     checkpoint
+	classical []int
+	// forced outcomes and rerolls of the gates before the snapshot when it was taken
+	outcomes map[int]int
+	rolls    map[int]int64
+	// last use, for evicting the least recently used
+	used int64
+}
+
+// what every snapshot depends on besides outcomes, a change drops all of them. the
+// gates, noise model and initial state are compared by pointer
+type snapshotKey struct {
+	backend string
+	seed    int64
+	gates   *CircuitGate
+	count   int
+	noise   *NoiseModel
+	initial *InitialState
+	bond    int
+}
+
+type snapshotCache struct {
+	// the circuit the snapshots belong to, the first to run after the budget was set.
+	// value copies of it share the pointer but run without snapshots
+	owner     *Circuit
+	key       snapshotKey
+	budget    int
+	bytes     int
+	tick      int64
+	snapshots map[int]*snapshot
+}
+
+// lets backends keep up to bytes of state snapshots to resume from, 0 turns them off.
+// it also drops every snapshot taken so far, call it again after editing Gates in place.
+// Noise and Initial are told apart by pointer, so assigning a new model or initial state
+// starts over on its own but changing the one already set needs this call too. the
+// snapshots belong to the first circuit that runs afterwards, copies of it don't use them
+func (c *Circuit) SetSnapshotBudget(bytes int) {
+	if bytes <= 0 {
+		c.snapshots = nil
+		return
+	}
+	c.snapshots = &snapshotCache{budget: bytes, snapshots: make(map[int]*snapshot)}
+}
+
+// memory held by snapshots right now, in bytes
+func (c *Circuit) SnapshotBytes() int {
+	if c.snapshots == nil {
+		return 0
+	}
+	return c.snapshots.bytes
+}
+
+// the circuit's snapshots, nil when there are none or they belong to the circuit this
+// one was copied from
+func (c *Circuit) ownSnapshots() *snapshotCache {
+	if c.snapshots == nil {
+		return nil
+	}
+	if c.snapshots.owner == nil {
+		c.snapshots.owner = c
+	}
+	if c.snapshots.owner != c {
+		return nil
+	}
+	return c.snapshots
+}
+
+// runs backend's state up to barrier n, starting from the nearest usable snapshot or
+// from fresh when there is none, and snapshots it along the way. the returned state
+// and register are the caller's to change
+func (c *Circuit) advance(backend string, atBarrier int, fresh func() (checkpoint, error)) (checkpoint, []int, error) {
+	cache := c.ownSnapshots()
+	if cache == nil {
+		state, err := fresh()
+		if err != nil {
+			return nil, nil, err
+		}
+		classical := make([]int, c.NumCbits())
+		if err := state.run(c, classical, 0, atBarrier); err != nil {
+			return nil, nil, err
+		}
+		return state, classical, nil
+	}
+
+	key := snapshotKey{backend: backend, seed: c.Seed, count: len(c.Gates), noise: c.Noise, initial: c.Initial, bond: c.BondDimension}
+	if len(c.Gates) > 0 {
+		key.gates = &c.Gates[0]
+	}
+	if key != cache.key {
+		cache.key = key
+		cache.bytes = 0
+		cache.snapshots = make(map[int]*snapshot)
+	}
+
+	var state checkpoint
+	var classical []int
+	var nearest *snapshot
+	from := 0
+	for barrier, s := range cache.snapshots {
+		if barrier > from && barrier <= atBarrier && c.matchesOutcomes(s, barrier) {
+			nearest, from = s, barrier
+		}
+	}
+	if nearest != nil {
+		cache.tick++
+		nearest.used = cache.tick
+		state = nearest.state.clone()
+		classical = append([]int(nil), nearest.classical...)
+	} else {
+		var err error
+		if state, err = fresh(); err != nil {
+			return nil, nil, err
+		}
+		classical = make([]int, c.NumCbits())
+	}
+
+	// spacing such that snapshots over the whole circuit fit the budget
+	spacing := int(math.Ceil(float64(len(c.Gates)) * float64(state.size()) / float64(cache.budget)))
+	spacing = max(spacing, 1)
+	for from < atBarrier {
+		to := min((from/spacing+1)*spacing, atBarrier)
+		if err := state.run(c, classical, from, to); err != nil {
+			return nil, nil, err
+		}
+		from = to
+		c.saveSnapshot(from, state, classical)
+	}
+	return state, classical, nil
+}
+
+// keeps a copy of the state at barrier n, evicting the least recently used snapshots
+// until it fits
+func (c *Circuit) saveSnapshot(atBarrier int, state checkpoint, classical []int) {
+	cache := c.snapshots
+	size := state.size()
+	if size > cache.budget {
+		return
+	}
+	if old, ok := cache.snapshots[atBarrier]; ok {
+		cache.bytes -= old.state.size()
+		delete(cache.snapshots, atBarrier)
+	}
+	for cache.bytes+size > cache.budget {
+		oldest := -1
+		for barrier, s := range cache.snapshots {
+			if oldest < 0 || s.used < cache.snapshots[oldest].used {
+				oldest = barrier
+			}
+		}
+		cache.bytes -= cache.snapshots[oldest].state.size()
+		delete(cache.snapshots, oldest)
+	}
+
+	s := &snapshot{
+		state:     state.clone(),
+		classical: append([]int(nil), classical...),
+		outcomes:  make(map[int]int),
+		rolls:     make(map[int]int64),
+	}
+	for index, outcome := range c.Outcomes {
+		if index < atBarrier {
+			s.outcomes[index] = outcome
+		}
+	}
+	for index, rolls := range c.rolls {
+		if index < atBarrier && rolls != 0 {
+			s.rolls[index] = rolls
+		}
+	}
+	cache.tick++
+	s.used = cache.tick
+	cache.snapshots[atBarrier] = s
+	cache.bytes += size
+}
+
+// frees the snapshots taken after gate index, once its outcome changed they can't be used
+func (cache *snapshotCache) dropAfter(index int) {
+	if cache == nil {
+		return
+	}
+	for barrier, s := range cache.snapshots {
+		if barrier > index {
+			cache.bytes -= s.state.size()
+			delete(cache.snapshots, barrier)
+		}
+	}
+}
+
+// whether the gates before barrier n still have the outcomes they had when s was taken
+func (c *Circuit) matchesOutcomes(s *snapshot, atBarrier int) bool {
+	outcomes, rolls := 0, 0
+	for index, outcome := range c.Outcomes {
+		if index >= atBarrier {
+			continue
+		}
+		if forced, ok := s.outcomes[index]; !ok || forced != outcome {
+			return false
+		}
+		outcomes++
+	}
+	for index, count := range c.rolls {
+		if index >= atBarrier || count == 0 {
+			continue
+		}
+		if s.rolls[index] != count {
+			return false
+		}
+		rolls++
+	}
+	return outcomes == len(s.outcomes) && rolls == len(s.rolls)
+}
+
+// the backends' states as checkpoints
+
+type stateVectorCheckpoint struct {
+	state     []complex128
+	numQubits int
+}
+
+func (s *stateVectorCheckpoint) run(c *Circuit, classical []int, from, to int) error {
+	return c.runGates(s.state, s.numQubits, classical, from, to, false)
+}
+
+func (s *stateVectorCheckpoint) clone() checkpoint {
+	return &stateVectorCheckpoint{append([]complex128(nil), s.state...), s.numQubits}
+}
+
+func (s *stateVectorCheckpoint) size() int {
+	return 16 * len(s.state)
+}
+
+type densityCheckpoint struct {
+	rho       Matrix
+	numQubits int
+}
+
+func (d *densityCheckpoint) run(c *Circuit, classical []int, from, to int) error {
+	return c.runDensityGates(&d.rho, d.numQubits, classical, from, to)
+}
+
+func (d *densityCheckpoint) clone() checkpoint {
+	rho := NewMatrix(d.rho.Rows, d.rho.Cols)
+	for i := range rho.Data {
+		copy(rho.Data[i], d.rho.Data[i])
+	}
+	return &densityCheckpoint{rho, d.numQubits}
+}
+
+func (d *densityCheckpoint) size() int {
+	return 16 * d.rho.Rows * d.rho.Cols
+}
+
+type stabilizerCheckpoint struct {
+	t *tableau
+}
+
+func (s stabilizerCheckpoint) run(c *Circuit, classical []int, from, to int) error {
+	return c.runStabilizerGates(s.t, classical, from, to)
+}
+
+func (s stabilizerCheckpoint) clone() checkpoint {
+	return stabilizerCheckpoint{s.t.clone()}
+}
+
+func (s stabilizerCheckpoint) size() int {
+	return 16*len(s.t.x)*len(s.t.x[0]) + len(s.t.r)
+}
+
+type mpsCheckpoint struct {
+	m *mps
+}
+
+func (s mpsCheckpoint) run(c *Circuit, classical []int, from, to int) error {
+	return c.runMPSGates(s.m, classical, from, to)
+}
+
+func (s mpsCheckpoint) clone() checkpoint {
+	return mpsCheckpoint{s.m.clone()}
+}
+
+func (s mpsCheckpoint) size() int {
+	size := 0
+	for _, site := range s.m.sites {
+		size += 16 * len(site.data)
+	}
+	return size
+}
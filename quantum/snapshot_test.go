@@ -0,0 +1,179 @@
+package quantum
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+func sameResult(a, b Result) bool {
+	if len(a.Probabilities) != len(b.Probabilities) || len(a.StateVector) != len(b.StateVector) {
+		return false
+	}
+	for key, p := range a.Probabilities {
+		if math.Abs(p-b.Probabilities[key]) > 1e-12 {
+			return false
+		}
+	}
+	for key, amplitude := range a.StateVector {
+		if cmplx.Abs(amplitude-b.StateVector[key]) > 1e-12 {
+			return false
+		}
+	}
+	for i := range a.ClassicalRegister {
+		if a.ClassicalRegister[i] != b.ClassicalRegister[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSnapshotsMatchFreshRuns(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	single := []string{"h", "x", "s", "z"}
+	double := []string{"cnot", "cz", "swap"}
+	for _, backend := range []string{BackendStateVector, BackendDensity, BackendStabilizer, BackendMPS} {
+		gates := randomCircuit(rng, 4, 60, single, double)
+		for g := 12; g < len(gates); g += 13 {
+			a := rng.Intn(4)
+			gates[g] = fmt.Sprintf("m%d->%d", a, a)
+		}
+		fresh, err := NewCircuit(gates)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fresh.Backend = backend
+		fresh.Seed = 3
+		cached, _ := NewCircuit(gates)
+		cached.Backend = backend
+		cached.Seed = 3
+		// room for a handful of snapshots so some get evicted
+		cached.SetSnapshotBudget(8 * 4096)
+
+		// step forward, back and jump around like the interactive view does
+		barriers := []int{1, 2, 3, 10, 9, 8, 30, 60, 59, 12, 13, 45, 1, 60}
+		for step := 0; step < 40; step++ {
+			barriers = append(barriers, 1+rng.Intn(len(gates)))
+		}
+		for i, barrier := range barriers {
+			if i == 20 {
+				// forcing an outcome drops the snapshots after it
+				if err := cached.SetOutcome(12, 1); err != nil {
+					t.Fatal(err)
+				}
+				fresh.SetOutcome(12, 1)
+			}
+			if i == 30 {
+				cached.Reroll(25)
+				fresh.Reroll(25)
+			}
+			want, wantErr := fresh.ExecuteToBarrier(barrier)
+			got, err := cached.ExecuteToBarrier(barrier)
+			if (err == nil) != (wantErr == nil) {
+				t.Fatalf("%s: barrier %d: got error %v, want %v", backend, barrier, err, wantErr)
+			}
+			if err == nil && !sameResult(got, want) {
+				t.Fatalf("%s: barrier %d differs from a fresh run", backend, barrier)
+			}
+			if cached.SnapshotBytes() > 8*4096 {
+				t.Fatalf("%s: snapshots hold %d bytes, over the budget", backend, cached.SnapshotBytes())
+			}
+		}
+	}
+}
+
+func TestSnapshotsResumeFromNearestBarrier(t *testing.T) {
+	gates := []string{"h0", "cnot0,1", "x2", "h1", "m0->0", "t1", "cnot1,2"}
+	c, _ := NewCircuit(gates)
+	c.SetSnapshotBudget(1 << 20)
+	if _, err := c.ExecuteToBarrier(5); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.snapshots.snapshots[5]; !ok {
+		t.Fatal("no snapshot at the barrier that was run")
+	}
+
+	// a snapshot that no longer matches the outcomes isn't resumed from
+	c.Outcomes = map[int]int{4: 1}
+	got, err := c.ExecuteToBarrier(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ClassicalRegister[0] != 1 {
+		t.Errorf("forced outcome ignored, register is %v", got.ClassicalRegister)
+	}
+
+	// changing the seed drops every snapshot
+	c.Seed++
+	c.ExecuteToBarrier(2)
+	for barrier := range c.snapshots.snapshots {
+		if barrier > 2 {
+			t.Errorf("snapshot at barrier %d kept after the seed changed", barrier)
+		}
+	}
+
+	c.SetSnapshotBudget(0)
+	if _, err := c.ExecuteToBarrier(7); err != nil || c.SnapshotBytes() != 0 {
+		t.Errorf("snapshots kept after turning them off: %v", err)
+	}
+}
+
+func TestSnapshotsFollowTheNoiseModel(t *testing.T) {
+	c, _ := NewCircuit([]string{"x0", "i0", "i0"})
+	c.Backend = BackendDensity
+	c.SetSnapshotBudget(1 << 20)
+	c.Noise = &NoiseModel{GateErrors: map[string]float64{"x": 0.5}}
+	before, err := c.ExecuteToBarrier(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// editing the model in place keeps the old snapshots until the budget is set again
+	c.Noise.GateErrors["x"] = 0
+	c.SetSnapshotBudget(1 << 20)
+	after, err := c.ExecuteToBarrier(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(after.Probabilities["1"]-1) > 1e-9 || sameResult(before, after) {
+		t.Errorf("P(1) = %v after removing the x error, want 1", after.Probabilities["1"])
+	}
+
+	// a new model starts over on its own
+	c.Noise = &NoiseModel{GateErrors: map[string]float64{"x": 0.5}}
+	again, err := c.ExecuteToBarrier(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameResult(before, again) {
+		t.Errorf("assigning a new noise model reused stale snapshots")
+	}
+}
+
+func TestSnapshotsBelongToOneCircuit(t *testing.T) {
+	gates := []string{"h0", "cnot0,1", "m0->0", "x1?c0==1", "h1"}
+	c, _ := NewCircuit(gates)
+	c.SetSnapshotBudget(1 << 20)
+	if _, err := c.ExecuteToBarrier(5); err != nil {
+		t.Fatal(err)
+	}
+	bytes, key := c.SnapshotBytes(), c.snapshots.key
+
+	// a value copy with another seed runs without touching them
+	copied := c
+	copied.Seed = 42
+	if err := copied.SetOutcome(2, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := copied.ExecuteToBarrier(5); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SampleToBarrier(5, 50, 9); err != nil {
+		t.Fatal(err)
+	}
+	if c.SnapshotBytes() != bytes || c.snapshots.key != key || len(c.snapshots.snapshots) == 0 {
+		t.Errorf("a copy of the circuit changed its snapshots")
+	}
+}
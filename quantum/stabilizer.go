@@ -0,0 +1,613 @@
+package quantum
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"math/rand"
+	"strings"
+)
+
+// stabilizer simulation (Aaronson & Gottesman, "Improved simulation of stabilizer circuits")
+//
+// a state reachable with Clifford gates is fixed by n commuting Pauli strings, its
+// stabilizer generators. the tableau keeps those n rows plus n destabilizer rows as
+// bit-packed x/z parts and a sign bit, so gates cost O(n) and measurements O(n²/64)
+// instead of the 2^n amplitudes the state vector needs. column j is wire j
+
+type tableau struct {
+	n int
+	// rows 0..n-1 are destabilizers, n..2n-1 stabilizers and 2n is scratch
+	x, z [][]uint64
+	// the row's sign is (-1)^r
+	r []uint8
+}
+
+// tableau of |0...0⟩, destabilizer i is X_i and stabilizer i is Z_i
+func newTableau(n int) *tableau {
+	words := (n + 63) / 64
+	t := &tableau{
+		n: n,
+		x: make([][]uint64, 2*n+1),
+		z: make([][]uint64, 2*n+1),
+		r: make([]uint8, 2*n+1),
+	}
+	for i := range t.x {
+		t.x[i] = make([]uint64, words)
+		t.z[i] = make([]uint64, words)
+	}
+	for i := 0; i < n; i++ {
+		setBit(t.x[i], i, 1)
+		setBit(t.z[i+n], i, 1)
+	}
+	return t
+}
+
+func bit(row []uint64, j int) uint64 {
+	return row[j/64] >> (j % 64) & 1
+}
+
+func setBit(row []uint64, j int, v uint64) {
+	row[j/64] = row[j/64]&^(1<<(j%64)) | v<<(j%64)
+}
+
+func (t *tableau) clone() *tableau {
+	out := &tableau{
+		n: t.n,
+		x: make([][]uint64, len(t.x)),
+		z: make([][]uint64, len(t.z)),
+		r: append([]uint8(nil), t.r...),
+	}
+	for i := range t.x {
+		out.x[i] = append([]uint64(nil), t.x[i]...)
+		out.z[i] = append([]uint64(nil), t.z[i]...)
+	}
+	return out
+}
+
+func (t *tableau) hadamard(a int) {
+	for i := 0; i < 2*t.n; i++ {
+		xa, za := bit(t.x[i], a), bit(t.z[i], a)
+		t.r[i] ^= uint8(xa & za)
+		setBit(t.x[i], a, za)
+		setBit(t.z[i], a, xa)
+	}
+}
+
+func (t *tableau) phase(a int) {
+	for i := 0; i < 2*t.n; i++ {
+		xa, za := bit(t.x[i], a), bit(t.z[i], a)
+		t.r[i] ^= uint8(xa & za)
+		setBit(t.z[i], a, za^xa)
+	}
+}
+
+// X, Y and Z only flip the sign of rows that anticommute with them
+func (t *tableau) pauli(a int, flipsX, flipsZ bool) {
+	for i := 0; i < 2*t.n; i++ {
+		if flipsX && bit(t.z[i], a) == 1 {
+			t.r[i] ^= 1
+		}
+		if flipsZ && bit(t.x[i], a) == 1 {
+			t.r[i] ^= 1
+		}
+	}
+}
+
+func (t *tableau) cnot(control, target int) {
+	for i := 0; i < 2*t.n; i++ {
+		xc, zc := bit(t.x[i], control), bit(t.z[i], control)
+		xt, zt := bit(t.x[i], target), bit(t.z[i], target)
+		t.r[i] ^= uint8(xc & zt & (xt ^ zc ^ 1))
+		setBit(t.x[i], target, xt^xc)
+		setBit(t.z[i], control, zc^zt)
+	}
+}
+
+func (t *tableau) cz(a, b int) {
+	t.hadamard(b)
+	t.cnot(a, b)
+	t.hadamard(b)
+}
+
+func (t *tableau) swap(a, b int) {
+	for i := 0; i < 2*t.n; i++ {
+		xa, za := bit(t.x[i], a), bit(t.z[i], a)
+		setBit(t.x[i], a, bit(t.x[i], b))
+		setBit(t.z[i], a, bit(t.z[i], b))
+		setBit(t.x[i], b, xa)
+		setBit(t.z[i], b, za)
+	}
+}
+
+// row h -> row i * row h, tracking the power of i the Pauli products pick up
+func (t *tableau) rowsum(h, i int) {
+	sum := 2*int(t.r[h]) + 2*int(t.r[i])
+	for w := range t.x[h] {
+		x1, z1, x2, z2 := t.x[i][w], t.z[i][w], t.x[h][w], t.z[h][w]
+		// XZ = -iY, ZY = -iX, YX = -iZ and the reverse orders give +i
+		plus := x1&z1&^x2&z2 | x1&^z1&x2&z2 | ^x1&z1&x2&^z2
+		minus := x1&z1&x2&^z2 | x1&^z1&^x2&z2 | ^x1&z1&x2&z2
+		sum += bits.OnesCount64(plus) - bits.OnesCount64(minus)
+		t.x[h][w] ^= x1
+		t.z[h][w] ^= z1
+	}
+	if (sum%4+4)%4 == 2 {
+		t.r[h] = 1
+	} else {
+		t.r[h] = 0
+	}
+}
+
+// measures wire a in the Z basis; choose picks the outcome given the chance of reading 1
+func (t *tableau) measure(a int, choose func(probabilityOne float64) (int, error)) (int, error) {
+	n := t.n
+	p := -1
+	for i := n; i < 2*n; i++ {
+		if bit(t.x[i], a) == 1 {
+			p = i
+			break
+		}
+	}
+
+	// no stabilizer anticommutes with Z_a, so ±Z_a is already in the group
+	if p < 0 {
+		scratch := 2 * n
+		for w := range t.x[scratch] {
+			t.x[scratch][w], t.z[scratch][w] = 0, 0
+		}
+		t.r[scratch] = 0
+		for i := 0; i < n; i++ {
+			if bit(t.x[i], a) == 1 {
+				t.rowsum(scratch, i+n)
+			}
+		}
+		outcome := int(t.r[scratch])
+		if _, err := choose(float64(outcome)); err != nil {
+			return 0, err
+		}
+		return outcome, nil
+	}
+
+	outcome, err := choose(0.5)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < 2*n; i++ {
+		if i != p && bit(t.x[i], a) == 1 {
+			t.rowsum(i, p)
+		}
+	}
+	copy(t.x[p-n], t.x[p])
+	copy(t.z[p-n], t.z[p])
+	t.r[p-n] = t.r[p]
+	for w := range t.x[p] {
+		t.x[p][w], t.z[p][w] = 0, 0
+	}
+	setBit(t.z[p], a, 1)
+	t.r[p] = uint8(outcome)
+	return outcome, nil
+}
+
+// stabilizer generator i as a signed Pauli string, wire 0 first
+func (t *tableau) generator(i int) string {
+	var sb strings.Builder
+	row := t.n + i
+	if t.r[row] == 1 {
+		sb.WriteByte('-')
+	} else {
+		sb.WriteByte('+')
+	}
+	for j := 0; j < t.n; j++ {
+		sb.WriteByte("IXZY"[bit(t.x[row], j)+2*bit(t.z[row], j)])
+	}
+	return sb.String()
+}
+
+// ⟨P⟩ is 0 when P anticommutes with a stabilizer, otherwise ±P is the product of the
+// stabilizers whose destabilizers anticommute with it and the sign is read off that
+func (t *tableau) expectation(term PauliTerm) float64 {
+	words := len(t.x[0])
+	px, pz := make([]uint64, words), make([]uint64, words)
+	for wire, pauli := range term.Paulis {
+		if wire >= t.n {
+			// past the last wire is |0⟩, only Z leaves it alone
+			if pauli != 'Z' {
+				return 0
+			}
+			continue
+		}
+		if pauli == 'X' || pauli == 'Y' {
+			setBit(px, wire, 1)
+		}
+		if pauli == 'Z' || pauli == 'Y' {
+			setBit(pz, wire, 1)
+		}
+	}
+	anticommutes := func(row int) bool {
+		odd := 0
+		for w := range px {
+			odd += bits.OnesCount64(t.x[row][w]&pz[w] ^ t.z[row][w]&px[w])
+		}
+		return odd%2 == 1
+	}
+
+	for i := t.n; i < 2*t.n; i++ {
+		if anticommutes(i) {
+			return 0
+		}
+	}
+	scratch := 2 * t.n
+	for w := range t.x[scratch] {
+		t.x[scratch][w], t.z[scratch][w] = 0, 0
+	}
+	t.r[scratch] = 0
+	for i := 0; i < t.n; i++ {
+		if anticommutes(i) {
+			t.rowsum(scratch, i+t.n)
+		}
+	}
+	if t.r[scratch] == 1 {
+		return -1
+	}
+	return 1
+}
+
+// bitstrings a Z basis measurement of every wire can read, which are equally likely
+type stabilizerSupport struct {
+	n int
+	// one readable bitstring
+	offset []uint64
+	// every other one is offset xor some combination of these
+	basis [][]uint64
+}
+
+// the support of a stabilizer state is one outcome plus the span of the stabilizers' x parts
+func (t *tableau) support() *stabilizerSupport {
+	// reading 0 wherever the outcome is random gives one valid bitstring
+	scratch := t.clone()
+	offset := make([]uint64, len(t.x[0]))
+	for a := 0; a < t.n; a++ {
+		outcome, _ := scratch.measure(a, func(probabilityOne float64) (int, error) {
+			if probabilityOne == 1 {
+				return 1, nil
+			}
+			return 0, nil
+		})
+		setBit(offset, a, uint64(outcome))
+	}
+
+	return &stabilizerSupport{n: t.n, offset: offset, basis: gf2Basis(t.x[t.n : 2*t.n])}
+}
+
+// basis of the span of rows over GF(2), the rows are left untouched. clearing a row's
+// leading bit only touches higher bits so every kept row ends up with its own leading bit
+func gf2Basis(rows [][]uint64) [][]uint64 {
+	var basis [][]uint64
+	byLead := make(map[int][]uint64)
+	for _, original := range rows {
+		row := append([]uint64(nil), original...)
+		for lead := leadingBit(row); lead >= 0; lead = leadingBit(row) {
+			b, ok := byLead[lead]
+			if !ok {
+				byLead[lead] = row
+				basis = append(basis, row)
+				break
+			}
+			for w := range row {
+				row[w] ^= b[w]
+			}
+		}
+	}
+	return basis
+}
+
+// entanglement entropy of the wires in bits: the stabilizers cut down to those wires
+// span 2^(|A| + S) Pauli strings
+func (t *tableau) entropy(wires []int) float64 {
+	words := (2*len(wires) + 63) / 64
+	rows := make([][]uint64, t.n)
+	for i := range rows {
+		rows[i] = make([]uint64, words)
+		for j, wire := range wires {
+			setBit(rows[i], 2*j, bit(t.x[t.n+i], wire))
+			setBit(rows[i], 2*j+1, bit(t.z[t.n+i], wire))
+		}
+	}
+	return float64(len(gf2Basis(rows)) - len(wires))
+}
+
+// lowest set bit, -1 for an empty row
+func leadingBit(row []uint64) int {
+	for w, word := range row {
+		if word != 0 {
+			return w*64 + bits.TrailingZeros64(word)
+		}
+	}
+	return -1
+}
+
+func (s *stabilizerSupport) key(row []uint64) string {
+	key := make([]byte, s.n)
+	for j := range key {
+		key[j] = byte('0' + bit(row, j))
+	}
+	return string(key)
+}
+
+// every readable bitstring with its probability, nil when there are more than 2^maxStabilizerOutcomes
+func (s *stabilizerSupport) probabilities() map[string]float64 {
+	if len(s.basis) > maxStabilizerOutcomes {
+		return nil
+	}
+	probabilities := make(map[string]float64, 1<<len(s.basis))
+	p := 1 / float64(int(1)<<len(s.basis))
+	row := append([]uint64(nil), s.offset...)
+	// gray code order flips one basis row per step
+	for k := 0; k < 1<<len(s.basis); k++ {
+		if k > 0 {
+			flip := s.basis[bits.TrailingZeros(uint(k))]
+			for w := range row {
+				row[w] ^= flip[w]
+			}
+		}
+		probabilities[s.key(row)] = p
+	}
+	return probabilities
+}
+
+func (s *stabilizerSupport) sample(shots int, rng *rand.Rand) map[string]int {
+	counts := make(map[string]int)
+	row := make([]uint64, len(s.offset))
+	for i := 0; i < shots; i++ {
+		copy(row, s.offset)
+		for _, b := range s.basis {
+			if rng.Intn(2) == 1 {
+				for w := range row {
+					row[w] ^= b[w]
+				}
+			}
+		}
+		counts[s.key(row)]++
+	}
+	return counts
+}
+
+// true if every operation can run on the stabilizer backend
+func (c *Circuit) IsClifford() bool {
+	for _, gate := range c.Gates {
+		if !isClifford(gate.Gate) {
+			return false
+		}
+	}
+	return true
+}
+
+// angle as a number of quarter turns 0-3, false when it isn't a multiple of π/2
+func quarterTurns(angle float64) (int, bool) {
+	turns := angle / (math.Pi / 2)
+	k := math.Round(turns)
+	if math.Abs(turns-k) > 1e-9 {
+		return 0, false
+	}
+	return (int(k)%4 + 4) % 4, true
+}
+
+func isClifford(gate GateInterface) bool {
+	switch g := gate.(type) {
+	case IdentityGate, HadamardGate, PauliXGate, PauliYGate, PauliZGate, SGate, SXGate,
+		CNOTGate, CZGate, CYGate, SWAPGate, ISWAPGate, ECRGate, GlobalPhaseGate, MeasureGate, ResetGate:
+		return true
+	case PhaseGate:
+		_, ok := quarterTurns(g.lambda)
+		return ok
+	case CPhaseGate:
+		// CP(π) is CZ, other angles aren't clifford
+		k, ok := quarterTurns(g.lambda)
+		return ok && k%2 == 0
+	case DaggerGate:
+		return isClifford(g.inner)
+	case ControlledGate:
+		// one control on X or Z is a CNOT or CZ, the others need T gates
+		switch g.inner.(type) {
+		case PauliXGate, PauliZGate:
+			return len(g.state) == 1
+		}
+	}
+	return false
+}
+
+// runs the circuit up to barrier n on a stabilizer tableau
+func (c *Circuit) executeStabilizer(atBarrier int) (Result, error) {
+	numQubits := c.NumQubits()
+	if numQubits > maxStabilizerWires+1 {
+		return Result{}, fmt.Errorf("%w, max: %d", ErrTooManyWires, maxStabilizerWires)
+	}
+
+	state, classical, err := c.advance(BackendStabilizer, atBarrier, func() (checkpoint, error) {
+		t := newTableau(numQubits)
+		if err := t.prepare(c.Initial); err != nil {
+			return nil, err
+		}
+		return stabilizerCheckpoint{t}, nil
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	t := state.(stabilizerCheckpoint).t
+
+	stabilizers := make([]string, numQubits)
+	for i := range stabilizers {
+		stabilizers[i] = t.generator(i)
+	}
+	support := t.support()
+
+	return Result{
+		Probabilities:     support.probabilities(),
+		ClassicalRegister: classical,
+		Stabilizers:       stabilizers,
+		sampler:           support,
+		expecter:          t,
+	}, nil
+}
+
+// starts the tableau from a product of the six Pauli eigenstates, each one
+// reached from |0⟩ by X, H and S
+func (t *tableau) prepare(initial *InitialState) error {
+	if initial == nil {
+		return nil
+	}
+	if !initial.IsProduct() {
+		return ErrProductStateOnly
+	}
+	for a, r := range initial.Wires {
+		if r == '1' || r == '-' || r == 'l' {
+			t.pauli(a, true, false)
+		}
+		if r != '0' && r != '1' {
+			t.hadamard(a)
+		}
+		if r == 'r' || r == 'l' {
+			t.phase(a)
+		}
+	}
+	return nil
+}
+
+// runs Clifford gates from..to-1 of the circuit on the tableau, measurement outcomes are written to classical
+func (c *Circuit) runStabilizerGates(t *tableau, classical []int, from, to int) error {
+	for i := from; i < to; i++ {
+		gate := c.Gates[i]
+		if !gate.Condition.Holds(classical) {
+			continue
+		}
+		if len(c.Noise.after(gate)) > 0 {
+			return fmt.Errorf("gate %d: %w", i+1, ErrNoiseUnsupported)
+		}
+
+		wires := gate.Wires
+		switch gate.Gate.(type) {
+		case MeasureGate, ResetGate:
+			outcome, err := t.measure(wires[0], func(probabilityOne float64) (int, error) {
+				return c.MeasurementOutcome(i, probabilityOne)
+			})
+			if err != nil {
+				return fmt.Errorf("gate %d: %w", i+1, err)
+			}
+			if _, ok := gate.Gate.(ResetGate); ok {
+				if outcome == 1 {
+					t.pauli(wires[0], true, false)
+				}
+				continue
+			}
+			classical[gate.Cbits[0]] = c.RecordedOutcome(i, wires[0], outcome)
+		case ChannelInterface:
+			return fmt.Errorf("gate %d: %w", i+1, ErrNoiseUnsupported)
+		default:
+			if !t.apply(gate.Gate, wires) {
+				return fmt.Errorf("gate %d: %w: %s", i+1, ErrNotClifford, gate.Gate.FullName())
+			}
+		}
+	}
+	return nil
+}
+
+// applies a Clifford gate, false when the gate isn't one
+func (t *tableau) apply(gate GateInterface, wires []int) bool {
+	switch g := gate.(type) {
+	case IdentityGate:
+	case HadamardGate:
+		t.hadamard(wires[0])
+	case PauliXGate:
+		t.pauli(wires[0], true, false)
+	case PauliYGate:
+		t.pauli(wires[0], true, true)
+	case PauliZGate:
+		t.pauli(wires[0], false, true)
+	case SGate:
+		t.phase(wires[0])
+	case PhaseGate:
+		k, ok := quarterTurns(g.lambda)
+		if !ok {
+			return false
+		}
+		for ; k > 0; k-- {
+			t.phase(wires[0])
+		}
+	case SXGate:
+		// √X = HSH
+		t.hadamard(wires[0])
+		t.phase(wires[0])
+		t.hadamard(wires[0])
+	case CNOTGate:
+		t.cnot(wires[0], wires[1])
+	case CZGate:
+		t.cz(wires[0], wires[1])
+	case CYGate:
+		// Y = SXS†, and S† = S³
+		for k := 0; k < 3; k++ {
+			t.phase(wires[1])
+		}
+		t.cnot(wires[0], wires[1])
+		t.phase(wires[1])
+	case CPhaseGate:
+		if !isClifford(g) {
+			return false
+		}
+		if k, _ := quarterTurns(g.lambda); k == 2 {
+			t.cz(wires[0], wires[1])
+		}
+	case SWAPGate:
+		t.swap(wires[0], wires[1])
+	case ISWAPGate:
+		// iSWAP = (S⊗S) SWAP CZ
+		t.cz(wires[0], wires[1])
+		t.swap(wires[0], wires[1])
+		t.phase(wires[0])
+		t.phase(wires[1])
+	case ECRGate:
+		// ECR = X₀ RZX(π/2), and RZX(π/2) is (S⊗S) CZ conjugated by H on the second wire
+		t.hadamard(wires[1])
+		t.phase(wires[0])
+		t.phase(wires[1])
+		t.cz(wires[0], wires[1])
+		t.hadamard(wires[1])
+		t.pauli(wires[0], true, false)
+	case GlobalPhaseGate:
+		// the tableau has no global phase
+	case DaggerGate:
+		switch inner := g.inner.(type) {
+		case SGate, SXGate, ISWAPGate:
+			// these have order 4, g† = g³
+			for k := 0; k < 3; k++ {
+				if !t.apply(inner, wires) {
+					return false
+				}
+			}
+			return true
+		case PhaseGate:
+			return t.apply(PhaseShift(-inner.lambda), wires)
+		}
+		// the others are their own inverses
+		return t.apply(g.inner, wires)
+	case ControlledGate:
+		if !isClifford(g) {
+			return false
+		}
+		// a control on |0⟩ is a control on |1⟩ between two X gates
+		if g.state == "0" {
+			t.pauli(wires[0], true, false)
+		}
+		if _, ok := g.inner.(PauliXGate); ok {
+			t.cnot(wires[0], wires[1])
+		} else {
+			t.cz(wires[0], wires[1])
+		}
+		if g.state == "0" {
+			t.pauli(wires[0], true, false)
+		}
+	default:
+		return false
+	}
+	return true
+}
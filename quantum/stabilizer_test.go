@@ -0,0 +1,196 @@
+package quantum
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestStabilizerMatchesStateVector(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	single := []string{"i", "h", "x", "y", "z", "s", "p", "sdg", "sx", "sxdg", "gphase"}
+	double := []string{"cnot", "cz", "swap", "cy", "iswap", "ecr"}
+
+	for trial := 0; trial < 50; trial++ {
+		numQubits := 1 + rng.Intn(5)
+		gates := randomCircuit(rng, numQubits, 30, single, double)
+
+		circuit, err := NewCircuit(gates)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !circuit.IsClifford() {
+			t.Fatalf("%v should be clifford", gates)
+		}
+		want, err := circuit.ExecuteToBarrier(len(gates))
+		if err != nil {
+			t.Fatal(err)
+		}
+		circuit.Backend = BackendStabilizer
+		got, err := circuit.ExecuteToBarrier(len(gates))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for key, p := range want.Probabilities {
+			if p > 1e-9 && math.Abs(got.Probabilities[key]-p) > 1e-9 {
+				t.Fatalf("%v: P(%s) = %v, state vector gives %v", gates, key, got.Probabilities[key], p)
+			}
+		}
+		for key := range got.Probabilities {
+			if want.Probabilities[key] < 1e-9 {
+				t.Fatalf("%v: stabilizer lists %s which the state vector never reads", gates, key)
+			}
+		}
+	}
+}
+
+func TestStabilizerGenerators(t *testing.T) {
+	tests := map[string][]string{
+		"h0 cnot0,1": {"+XX", "+ZZ"},
+		"x0 h1 z1":   {"-ZI", "-IX"},
+		"h0 s0":      {"+Y"},
+	}
+	for gates, want := range tests {
+		circuit, _ := NewCircuit(strings.Split(gates, " "))
+		circuit.Backend = BackendStabilizer
+		result, err := circuit.ExecuteToBarrier(len(circuit.Gates))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(result.Stabilizers, " ") != strings.Join(want, " ") {
+			t.Errorf("%s: stabilizers %v, want %v", gates, result.Stabilizers, want)
+		}
+	}
+}
+
+func TestStabilizerWideGHZ(t *testing.T) {
+	const numQubits = 1000
+	gates := []string{"h0"}
+	for i := 1; i < numQubits; i++ {
+		gates = append(gates, fmt.Sprintf("cnot%d,%d", i-1, i))
+	}
+	gates = append(gates, fmt.Sprintf("m%d->0", numQubits/2))
+
+	circuit, err := NewCircuit(gates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	circuit.Backend = BackendStabilizer
+	circuit.Seed = 4
+
+	result, err := circuit.ExecuteToBarrier(len(gates))
+	if err != nil {
+		t.Fatal(err)
+	}
+	outcome := strings.Repeat(fmt.Sprint(result.ClassicalRegister[0]), numQubits)
+	if len(result.Probabilities) != 1 || result.Probabilities[outcome] != 1 {
+		t.Errorf("measuring one wire of a GHZ state should fix the rest")
+	}
+
+	// before the measurement both all-zeros and all-ones are equally likely
+	circuit.Gates = circuit.Gates[:numQubits]
+	counts, err := circuit.Sample(200, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zeros, ones := strings.Repeat("0", numQubits), strings.Repeat("1", numQubits)
+	if len(counts) != 2 || counts[zeros]+counts[ones] != 200 || counts[zeros] < 70 || counts[ones] < 70 {
+		t.Errorf("unexpected GHZ counts %v", len(counts))
+	}
+}
+
+func TestStabilizerTooManyOutcomesToList(t *testing.T) {
+	var gates []string
+	for i := 0; i < 20; i++ {
+		gates = append(gates, fmt.Sprintf("h%d", i))
+	}
+	circuit, _ := NewCircuit(gates)
+	circuit.Backend = BackendStabilizer
+
+	result, err := circuit.ExecuteToBarrier(len(gates))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Probabilities != nil {
+		t.Errorf("expected 2^20 outcomes to be left unlisted")
+	}
+	counts, err := circuit.Sample(100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) < 95 {
+		t.Errorf("100 shots over 2^20 outcomes should rarely repeat, got %d distinct", len(counts))
+	}
+}
+
+func TestStabilizerMeasureAndReset(t *testing.T) {
+	circuit, _ := NewCircuit(strings.Split("h0 cnot0,1 m0->0 x2?c0==1 reset1", " "))
+	circuit.Backend = BackendStabilizer
+	if err := circuit.SetOutcome(2, 1); err != nil {
+		t.Fatal(err)
+	}
+	result, err := circuit.ExecuteToBarrier(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Probabilities["101"] != 1 || result.ClassicalRegister[0] != 1 {
+		t.Errorf("expected 101 after forcing c0=1, got %v", result.Probabilities)
+	}
+
+	// z1 is fixed to 0 after the cnot, so reading 1 is impossible
+	circuit, _ = NewCircuit(strings.Split("x0 x1 cnot0,1 m1->0", " "))
+	circuit.Backend = BackendStabilizer
+	circuit.SetOutcome(3, 1)
+	if _, err := circuit.ExecuteToBarrier(4); !errors.Is(err, ErrImpossibleOutcome) {
+		t.Errorf("expected ErrImpossibleOutcome, got %v", err)
+	}
+}
+
+func TestStabilizerRejectsNonClifford(t *testing.T) {
+	circuit, _ := NewCircuit(strings.Split("h0 t0", " "))
+	if circuit.IsClifford() {
+		t.Error("t0 is not clifford")
+	}
+	circuit.Backend = BackendStabilizer
+	if _, err := circuit.ExecuteToBarrier(2); !errors.Is(err, ErrNotClifford) {
+		t.Errorf("expected ErrNotClifford, got %v", err)
+	}
+}
+
+func TestStabilizerAnglesAndInverses(t *testing.T) {
+	// phases only show up in probabilities once the final hadamards interfere them
+	for _, gates := range []string{
+		"h0 h1 p0(pi) p1(-pi/2) h0 h1",
+		"h0 p0(3*pi/2) inv(p0(pi/2)) h0",
+		"h0 h1 cp0,1(pi) h1",
+		"h0 h1 cp0,1(-pi) cp1,0(2*pi) h0",
+		"h0 x1 iswap0,1' h0 h1",
+		"h0 sx1 iswap0,1 iswap1,0' ecr1,0 sxdg0 ecr0,1' h0 h1",
+		"h0 s1 cy0,1 h1 cy1,0' gphase0(pi/3) h0",
+	} {
+		circuit, err := NewCircuit(strings.Split(gates, " "))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !circuit.IsClifford() {
+			t.Fatalf("%q should be clifford", gates)
+		}
+		want := runCircuit(t, strings.Split(gates, " "), BackendStateVector)
+		got := runCircuit(t, strings.Split(gates, " "), BackendStabilizer)
+		for key, p := range want.Probabilities {
+			if math.Abs(got.Probabilities[key]-p) > 1e-9 {
+				t.Fatalf("%q: P(%s) = %v, state vector gives %v", gates, key, got.Probabilities[key], p)
+			}
+		}
+	}
+
+	for _, gate := range []string{"p0(pi/4)", "cp0,1(pi/2)", "ch0,1", "rzz0,1(pi/2)", "cswap0,1,2"} {
+		if circuit, _ := NewCircuit([]string{gate}); circuit.IsClifford() {
+			t.Errorf("%s is not clifford", gate)
+		}
+	}
+}
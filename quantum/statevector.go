@@ -0,0 +1,390 @@
+package quantum
+
+import (
+	"fmt"
+	"math/cmplx"
+	"math/rand"
+	"sort"
+)
+
+// in-place state vector kernels
+//
+// wire w lives at bit (numQubits-1-w) of a basis index, so wire 0 is the most
+// significant bit and matches the left-most character of a result key. the
+// kernels walk the amplitude pairs/quads/octets a gate mixes and update them in
+// place, which is O(2^n) memory and O(2^n) time per gate instead of building a
+// dense 2^n x 2^n matrix.
+
+// bit mask of a wire inside a basis index
+func wireMask(wire, numQubits int) int {
+	return 1 << (numQubits - 1 - wire)
+}
+
+// spreads the bits of i around the cleared bits in masks (ascending), so counting
+// i up to 2^(n-k) visits every base index with those k bits set to 0 exactly once
+func insertZeroBits(i int, masks []int) int {
+	for _, m := range masks {
+		low := i & (m - 1)
+		i = (i-low)<<1 | low
+	}
+	return i
+}
+
+// masks of the given wires, sorted ascending for insertZeroBits
+func sortedMasks(wires []int, numQubits int) []int {
+	masks := make([]int, len(wires))
+	for i, wire := range wires {
+		masks[i] = wireMask(wire, numQubits)
+	}
+	sort.Ints(masks)
+	return masks
+}
+
+// mixes amplitude pairs that only differ in the target wire
+func applySingleQubitKernel(state []complex128, numQubits int, gate Matrix, wire int) {
+	g := gate.Data
+	m := wireMask(wire, numQubits)
+	for base := 0; base < len(state); base += 2 * m {
+		for i := base; i < base+m; i++ {
+			a0, a1 := state[i], state[i|m]
+			state[i] = g[0][0]*a0 + g[0][1]*a1
+			state[i|m] = g[1][0]*a0 + g[1][1]*a1
+		}
+	}
+}
+
+// mixes amplitude quads; wires[0] is the most significant bit of the gate's own index
+func applyTwoQubitKernel(state []complex128, numQubits int, gate Matrix, wires []int) {
+	g := gate.Data
+	m0 := wireMask(wires[0], numQubits)
+	m1 := wireMask(wires[1], numQubits)
+	masks := sortedMasks(wires, numQubits)
+	var idx [4]int
+	var amp [4]complex128
+	for n := 0; n < len(state)>>2; n++ {
+		i := insertZeroBits(n, masks)
+		idx = [4]int{i, i | m1, i | m0, i | m0 | m1}
+		for k := range idx {
+			amp[k] = state[idx[k]]
+		}
+		for r := 0; r < 4; r++ {
+			var sum complex128
+			for k := 0; k < 4; k++ {
+				sum += g[r][k] * amp[k]
+			}
+			state[idx[r]] = sum
+		}
+	}
+}
+
+// mixes amplitude octets; wires[0] is the most significant bit of the gate's own index
+func applyThreeQubitKernel(state []complex128, numQubits int, gate Matrix, wires []int) {
+	g := gate.Data
+	m0 := wireMask(wires[0], numQubits)
+	m1 := wireMask(wires[1], numQubits)
+	m2 := wireMask(wires[2], numQubits)
+	masks := sortedMasks(wires, numQubits)
+	var idx [8]int
+	var amp [8]complex128
+	for n := 0; n < len(state)>>3; n++ {
+		i := insertZeroBits(n, masks)
+		for k := 0; k < 8; k++ {
+			j := i
+			if k&4 != 0 {
+				j |= m0
+			}
+			if k&2 != 0 {
+				j |= m1
+			}
+			if k&1 != 0 {
+				j |= m2
+			}
+			idx[k] = j
+			amp[k] = state[j]
+		}
+		for r := 0; r < 8; r++ {
+			var sum complex128
+			for k := 0; k < 8; k++ {
+				sum += g[r][k] * amp[k]
+			}
+			state[idx[r]] = sum
+		}
+	}
+}
+
+// mixes blocks of 2^k amplitudes for a gate on any number of wires; wires[0] is
+// the most significant bit of the gate's own index, so wire order picks which
+// wire plays control/target no matter how the wires are spaced
+func applyMultiQubitKernel(state []complex128, numQubits int, gate Matrix, wires []int) {
+	g := gate.Data
+	k := len(wires)
+	dim := 1 << k
+
+	// offsets[j] sets the wires that are 1 in the gate's local basis state j
+	offsets := make([]int, dim)
+	for j := 0; j < dim; j++ {
+		for t, wire := range wires {
+			if j&(1<<(k-1-t)) != 0 {
+				offsets[j] |= wireMask(wire, numQubits)
+			}
+		}
+	}
+
+	masks := sortedMasks(wires, numQubits)
+	amp := make([]complex128, dim)
+	for n := 0; n < len(state)>>k; n++ {
+		i := insertZeroBits(n, masks)
+		for j, offset := range offsets {
+			amp[j] = state[i|offset]
+		}
+		for r := 0; r < dim; r++ {
+			var sum complex128
+			for j := 0; j < dim; j++ {
+				sum += g[r][j] * amp[j]
+			}
+			state[i|offsets[r]] = sum
+		}
+	}
+}
+
+// applies gate to the target wires of the amplitudes whose control wires read state,
+// so a gate with many controls costs no more than its target part
+func applyControlledKernel(state []complex128, numQubits int, gate Matrix, controls []int, controlState string, targets []int) {
+	g := gate.Data
+	k := len(targets)
+	dim := 1 << k
+
+	fires := 0
+	for j, wire := range controls {
+		if controlState[j] == '1' {
+			fires |= wireMask(wire, numQubits)
+		}
+	}
+	offsets := make([]int, dim)
+	for j := 0; j < dim; j++ {
+		for t, wire := range targets {
+			if j&(1<<(k-1-t)) != 0 {
+				offsets[j] |= wireMask(wire, numQubits)
+			}
+		}
+	}
+
+	masks := sortedMasks(append(append([]int(nil), controls...), targets...), numQubits)
+	amp := make([]complex128, dim)
+	for n := 0; n < len(state)>>len(masks); n++ {
+		i := insertZeroBits(n, masks) | fires
+		for j, offset := range offsets {
+			amp[j] = state[i|offset]
+		}
+		for r := 0; r < dim; r++ {
+			var sum complex128
+			for j := 0; j < dim; j++ {
+				sum += g[r][j] * amp[j]
+			}
+			state[i|offsets[r]] = sum
+		}
+	}
+}
+
+// applies a gate to the given wires in place, wire order is honored
+func applyGateKernel(state []complex128, numQubits int, gate Matrix, wires []int) {
+	switch len(wires) {
+	case 1:
+		applySingleQubitKernel(state, numQubits, gate, wires[0])
+	case 2:
+		applyTwoQubitKernel(state, numQubits, gate, wires)
+	case 3:
+		applyThreeQubitKernel(state, numQubits, gate, wires)
+	default:
+		applyMultiQubitKernel(state, numQubits, gate, wires)
+	}
+}
+
+// runs the circuit up to barrier n on a state vector
+func (c *Circuit) executeStateVector(atBarrier int) (Result, error) {
+	numQubits := c.NumQubits()
+	if numQubits > maxWires+1 {
+		return Result{}, fmt.Errorf("%w, max: %d", ErrTooManyWires, maxWires)
+	}
+
+	// apply gates up to the barrier
+	state, classical, err := c.advance(BackendStateVector, atBarrier, func() (checkpoint, error) {
+		return &stateVectorCheckpoint{c.Initial.vector(numQubits), numQubits}, nil
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	stateVector := state.(*stateVectorCheckpoint).state
+
+	result := Result{
+		Amplitudes:        stateVector,
+		ClassicalRegister: classical,
+		sampler:           amplitudeVector(stateVector),
+		expecter:          amplitudeVector(stateVector),
+	}
+	// labelling every basis state costs far more than running the gates, so wide
+	// results only keep the amplitude slice
+	if numQubits <= maxListedWires {
+		labeledStateVector := make(map[string]complex128, len(stateVector))
+		for i, amplitude := range stateVector {
+			labeledStateVector[basisKey(i, numQubits)] = amplitude
+		}
+		result.StateVector = labeledStateVector
+		result.StateVectorSymbolic = SymbofyMap(labeledStateVector)
+		result.Probabilities = Probabilities(labeledStateVector)
+	}
+	return result, nil
+}
+
+// bitstring of basis index i, wire 0 first
+func basisKey(i, numQubits int) string {
+	key := make([]byte, numQubits)
+	for w := range key {
+		key[w] = byte('0' + i>>(numQubits-1-w)&1)
+	}
+	return string(key)
+}
+
+// a result's amplitudes by basis index, answering what its maps would without labelling
+// every basis state
+type amplitudeVector []complex128
+
+func (v amplitudeVector) numQubits() int {
+	numQubits := 0
+	for 1<<numQubits < len(v) {
+		numQubits++
+	}
+	return numQubits
+}
+
+// Σ conj(ψ[i^xMask]) i^ys (-1)^|i&zMask| ψ[i]
+func (v amplitudeVector) expectation(term PauliTerm) float64 {
+	xMask, zMask, ys, ok := pauliMasks(term, v.numQubits())
+	if !ok {
+		return 0
+	}
+	sum := complex(0, 0)
+	for i, amplitude := range v {
+		if amplitude != 0 {
+			sum += cmplx.Conj(v[i^xMask]) * pauliPhase(i, zMask, ys) * amplitude
+		}
+	}
+	return real(sum)
+}
+
+// draws the same bitstrings sampling Probabilities would: the draws are sorted and
+// matched against the running total in one pass over the amplitudes
+func (v amplitudeVector) sample(shots int, rng *rand.Rand) map[string]int {
+	total := 0.0
+	last := -1
+	for i, amplitude := range v {
+		if p := real(amplitude)*real(amplitude) + imag(amplitude)*imag(amplitude); p > 0 {
+			total += p
+			last = i
+		}
+	}
+	counts := make(map[string]int)
+	if last < 0 {
+		return counts
+	}
+	draws := make([]float64, shots)
+	for i := range draws {
+		draws[i] = rng.Float64() * total
+	}
+	sort.Float64s(draws)
+
+	numQubits := v.numQubits()
+	running, d := 0.0, 0
+	for i, amplitude := range v {
+		p := real(amplitude)*real(amplitude) + imag(amplitude)*imag(amplitude)
+		if p <= 0 {
+			continue
+		}
+		running += p
+		n := 0
+		for d < len(draws) && (draws[d] <= running || i == last) {
+			d++
+			n++
+		}
+		if n > 0 {
+			counts[basisKey(i, numQubits)] += n
+		}
+	}
+	return counts
+}
+
+// runs gates from..to-1 of the circuit on the state vector in place, measurement outcomes are written to classical.
+// noise is only allowed when sampleNoise is set, then each channel picks one Kraus branch
+func (c *Circuit) runGates(stateVector []complex128, numQubits int, classical []int, from, to int, sampleNoise bool) error {
+	for i := from; i < to; i++ {
+		gate := c.Gates[i]
+		if !gate.Condition.Holds(classical) {
+			continue
+		}
+		switch g := gate.Gate.(type) {
+		case MeasureGate:
+			outcome, err := c.measureWire(stateVector, numQubits, i, gate.Wires[0])
+			if err != nil {
+				return fmt.Errorf("gate %d: %w", i+1, err)
+			}
+			classical[gate.Cbits[0]] = c.RecordedOutcome(i, gate.Wires[0], outcome)
+		case ResetGate:
+			if err := c.resetWire(stateVector, numQubits, i, gate.Wires[0]); err != nil {
+				return fmt.Errorf("gate %d: %w", i+1, err)
+			}
+		case ChannelInterface:
+			if !sampleNoise {
+				return fmt.Errorf("gate %d: %w", i+1, ErrNoiseUnsupported)
+			}
+			applyKrausBranch(stateVector, numQubits, g.Kraus(), gate.Wires, c.gateRand(i, 2))
+		default:
+			if err := applyGates(stateVector, []CircuitGate{gate}, numQubits); err != nil {
+				return err
+			}
+			noise := c.Noise.after(gate)
+			if len(noise) > 0 && !sampleNoise {
+				return fmt.Errorf("gate %d: %w", i+1, ErrNoiseUnsupported)
+			}
+			for j, op := range noise {
+				applyKrausBranch(stateVector, numQubits, op.channel.Kraus(), op.wires, c.gateRand(i, int64(3+j)))
+			}
+		}
+	}
+	return nil
+}
+
+// applies unitary gates in place on the state vector
+func applyGates(stateVector []complex128, gates []CircuitGate, numQubits int) error {
+	if len(stateVector) != 1<<numQubits {
+		return ErrInvalidWireCount
+	}
+	for _, gate := range gates {
+		for _, wire := range gate.Wires {
+			if wire < 0 || wire >= numQubits {
+				return ErrInvalidWireCount
+			}
+		}
+
+		// controlled gates only touch the amplitudes their controls select
+		controls := 0
+		g := gate.Gate
+		if controlled, ok := g.(ControlledGate); ok {
+			controls = len(controlled.state)
+			g = controlled.inner
+		}
+		data := g.Data()
+		if data.Rows != data.Cols {
+			return ErrGateMatrixNotSquare
+		}
+		if data.Rows != 1<<(len(gate.Wires)-controls) {
+			return ErrInvalidWireCount
+		}
+
+		if controls > 0 {
+			applyControlledKernel(stateVector, numQubits, data, gate.Wires[:controls], gate.Gate.(ControlledGate).state, gate.Wires[controls:])
+		} else {
+			applyGateKernel(stateVector, numQubits, data, gate.Wires)
+		}
+	}
+	return nil
+}
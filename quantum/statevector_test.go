@@ -0,0 +1,313 @@
+package quantum
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// every gate shipped in gates.go, at a non-trivial angle where it takes one
+func allGates() []GateInterface {
+	return []GateInterface{
+		Identity(2),
+		Hadamard(),
+		PauliX(),
+		PauliY(),
+		PauliZ(),
+		CNOT(),
+		SWAP(),
+		CZ(),
+		CCX(),
+		CCZ(),
+		T(),
+		S(),
+		PhaseShift(0.9),
+		Phase(),
+		Rx(0.3),
+		Ry(0.7),
+		Rz(1.1),
+		CRx(0.3),
+		CRy(0.7),
+		CRz(1.1),
+		Toffoli(),
+		SX(),
+		CY(),
+		CH(),
+		CPhase(0.5),
+		Fredkin(),
+		ISWAP(),
+		ECR(),
+		RXX(0.3),
+		RYY(0.7),
+		RZZ(1.1),
+		GlobalPhase(0.4),
+		U(0.3, 0.7, 1.1),
+		CU(1.1, 0.3, 0.7),
+		inverse(S()),
+		inverse(T()),
+		inverse(SX()),
+		inverse(U(0.3, 0.7, 1.1)),
+		inverse(CRy(0.7)),
+		inverse(ISWAP()),
+		inverse(ECR()),
+		controlled(Hadamard(), "1"),
+		controlled(Ry(0.7), "0"),
+		controlled(SWAP(), "10"),
+		controlled(inverse(T()), "01"),
+		controlled(CNOT(), "0"),
+	}
+}
+
+func controlled(g GateInterface, state string) GateInterface {
+	c, err := Controlled(g, state)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func inverse(g GateInterface) GateInterface {
+	d, err := Dagger(g)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func randomState(rng *rand.Rand, numQubits int) []complex128 {
+	state := make([]complex128, 1<<numQubits)
+	for i := range state {
+		state[i] = complex(rng.NormFloat64(), rng.NormFloat64())
+	}
+	return state
+}
+
+// reference application: permute the basis so the gate's wires come first in the
+// order given, apply gate ⊗ I as a dense matrix, then permute back
+func referenceApply(state []complex128, numQubits int, gate Matrix, wires []int) []complex128 {
+	order := append([]int(nil), wires...)
+	used := make(map[int]bool)
+	for _, wire := range wires {
+		used[wire] = true
+	}
+	for wire := 0; wire < numQubits; wire++ {
+		if !used[wire] {
+			order = append(order, wire)
+		}
+	}
+
+	// basis index i in wire order -> index in permuted order
+	permute := func(i int) int {
+		p := 0
+		for pos, wire := range order {
+			if i&wireMask(wire, numQubits) != 0 {
+				p |= wireMask(pos, numQubits)
+			}
+		}
+		return p
+	}
+
+	identity := Identity(1 << (numQubits - len(wires))).Data()
+	full := tensorGateMatrix(&gate, &identity)
+
+	permuted := NewMatrix(len(state), 1)
+	for i, amplitude := range state {
+		permuted.Data[permute(i)][0] = amplitude
+	}
+	applied := full.MustMultiply(&permuted)
+
+	out := make([]complex128, len(state))
+	for i := range out {
+		out[i] = applied.Data[permute(i)][0]
+	}
+	return out
+}
+
+func statesEqual(a, b []complex128) bool {
+	for i := range a {
+		if cmplx.Abs(a[i]-b[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+// all ordered choices of k distinct wires out of numQubits
+func wireOrders(numQubits, k int) [][]int {
+	if k == 0 {
+		return [][]int{{}}
+	}
+	var orders [][]int
+	for _, rest := range wireOrders(numQubits, k-1) {
+		for wire := 0; wire < numQubits; wire++ {
+			taken := false
+			for _, w := range rest {
+				if w == wire {
+					taken = true
+				}
+			}
+			if !taken {
+				orders = append(orders, append(append([]int(nil), rest...), wire))
+			}
+		}
+	}
+	return orders
+}
+
+func TestKernelMatchesPermutationReference(t *testing.T) {
+	const numQubits = 5
+	rng := rand.New(rand.NewSource(1))
+	for _, gate := range allGates() {
+		for _, wires := range wireOrders(numQubits, gate.WiresNeeded()) {
+			state := randomState(rng, numQubits)
+			want := referenceApply(state, numQubits, gate.Data(), wires)
+			applyGateKernel(state, numQubits, gate.Data(), wires)
+			if !statesEqual(state, want) {
+				t.Fatalf("%s on wires %v does not match reference", gate.Name(), wires)
+			}
+		}
+	}
+}
+
+func TestMultiQubitKernelMatchesPermutationReference(t *testing.T) {
+	const numQubits = 6
+	rng := rand.New(rand.NewSource(2))
+
+	// random 4-qubit matrix, the kernel does not rely on unitarity
+	gate := NewMatrix(16, 16)
+	for i := range gate.Data {
+		for j := range gate.Data[i] {
+			gate.Data[i][j] = complex(rng.NormFloat64(), rng.NormFloat64())
+		}
+	}
+
+	for _, wires := range [][]int{{0, 1, 2, 3}, {3, 2, 1, 0}, {5, 0, 3, 1}, {2, 4, 0, 5}} {
+		state := randomState(rng, numQubits)
+		want := referenceApply(state, numQubits, gate, wires)
+		applyGateKernel(state, numQubits, gate, wires)
+		if !statesEqual(state, want) {
+			t.Fatalf("4-qubit gate on wires %v does not match reference", wires)
+		}
+	}
+}
+
+func TestControlTargetOrder(t *testing.T) {
+	tests := []struct {
+		gates string
+		want  string
+	}{
+		// control on wire 1, target wire 0
+		{"x1 cnot1,0", "11"},
+		{"x0 cnot1,0", "10"},
+		// gap between control and target
+		{"x2 cnot2,0", "101"},
+		{"x0 cnot0,2", "101"},
+		// target is the last wire listed
+		{"x2 x1 toff2,1,0", "111"},
+		{"x2 x0 toff2,0,1", "111"},
+		{"x1 x0 toff2,1,0", "110"},
+		{"x0 swap0,3", "0001"},
+	}
+
+	for _, tt := range tests {
+		circuit, err := NewCircuit(strings.Split(tt.gates, " "))
+		if err != nil {
+			t.Fatalf("%q: %v", tt.gates, err)
+		}
+		result, err := circuit.ExecuteToBarrier(len(circuit.Gates))
+		if err != nil {
+			t.Fatalf("%q: %v", tt.gates, err)
+		}
+		if p := result.Probabilities[tt.want]; p < 1-1e-9 {
+			t.Errorf("%q: P(%s) = %v, want 1", tt.gates, tt.want, p)
+		}
+	}
+}
+
+func TestControlledKernelMatchesReference(t *testing.T) {
+	const numQubits = 7
+	rng := rand.New(rand.NewSource(3))
+	for _, tc := range []struct {
+		gate     GateInterface
+		controls []int
+		state    string
+		targets  []int
+	}{
+		{PauliX(), []int{0}, "1", []int{1}},
+		{Hadamard(), []int{6, 2}, "01", []int{4}},
+		{Ry(0.7), []int{1, 5, 3}, "101", []int{0}},
+		{SWAP(), []int{4, 0, 6}, "110", []int{2, 5}},
+		{CRx(0.3), []int{3, 1, 0, 6}, "0000", []int{5, 2}},
+		{CCZ(), []int{2, 6}, "11", []int{0, 4, 1}},
+	} {
+		gate, err := Controlled(tc.gate, tc.state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wires := append(append([]int(nil), tc.controls...), tc.targets...)
+		state := randomState(rng, numQubits)
+		want := referenceApply(state, numQubits, gate.Data(), wires)
+		applyControlledKernel(state, numQubits, tc.gate.Data(), tc.controls, tc.state, tc.targets)
+		if !statesEqual(state, want) {
+			t.Fatalf("%s on wires %v does not match reference", gate.Name(), wires)
+		}
+	}
+}
+
+func TestWideResultKeepsAmplitudes(t *testing.T) {
+	numQubits := maxListedWires + 1
+	gates := []string{"h0", "ry1(0.7)"}
+	for i := 2; i < numQubits; i++ {
+		gates = append(gates, fmt.Sprintf("h%d", i))
+	}
+	result := runCircuit(t, gates, BackendStateVector)
+	if result.StateVector != nil || result.Probabilities != nil || len(result.Amplitudes) != 1<<numQubits {
+		t.Fatalf("%d wires should only keep the amplitude slice", numQubits)
+	}
+
+	// the slice answers what the maps would have
+	o, _ := ParseObservable("X0 + Z1")
+	value, err := result.Expectation(o)
+	if err != nil || math.Abs(value-(1+math.Cos(0.7))) > 1e-9 {
+		t.Errorf("⟨X0 + Z1⟩ = %v, %v", value, err)
+	}
+	if entropy, err := result.Entropy([]int{0, 1}); err != nil || math.Abs(entropy) > 1e-9 {
+		t.Errorf("S(0, 1) = %v, %v", entropy, err)
+	}
+	labeled := Result{Probabilities: result.probabilityMap()}
+	for seed := int64(0); seed < 3; seed++ {
+		got := result.Sample(500, rand.New(rand.NewSource(seed)))
+		want := labeled.Sample(500, rand.New(rand.NewSource(seed)))
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("seed %d: sampling the slice differs from sampling the labelled probabilities", seed)
+		}
+	}
+
+	// the table lists the most likely states and sums up the rest
+	states, hidden, weight := tableStates(result)
+	if len(states) != maxTableRows || hidden != 1<<numQubits-maxTableRows {
+		t.Errorf("table lists %d states and hides %d", len(states), hidden)
+	}
+	for _, state := range states {
+		if state.Key[1] != '0' {
+			t.Errorf("%s listed ahead of likelier states", state.Key)
+		}
+	}
+	if total := weight + float64(maxTableRows)*states[0].Value; math.Abs(total-1) > 1e-9 {
+		t.Errorf("listed and hidden states hold %v", total)
+	}
+}
+
+func TestNarrowResultListsEveryAmplitude(t *testing.T) {
+	result := runCircuit(t, []string{"h0", "cnot0,1"}, BackendStateVector)
+	if len(result.StateVector) != 4 || len(result.Probabilities) != 4 {
+		t.Errorf("expected all 4 amplitudes, zeros included, got %v", result.StateVector)
+	}
+	if _, ok := result.StateVector["01"]; !ok {
+		t.Errorf("zero amplitude of 01 missing")
+	}
+}
@@ -10,7 +10,9 @@ import (
 var constants = map[string]complex128{
 	"0":                    0,
 	"1":                    1,
+	"-1":                   -1,
 	"i":                    1i,
+	"-i":                   -1i,
 	"1/sqrt(2)":            complex(1/math.Sqrt(2), 0),
 	"-1/sqrt(2)":           complex(-1/math.Sqrt(2), 0),
 	"1/sqrt(2)+i/2":        complex(1/math.Sqrt(2), 0.5),
@@ -0,0 +1,153 @@
+package quantum
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"strings"
+)
+
+// monte carlo quantum trajectories
+//
+// every trajectory is a plain state vector run where each noise channel picks one
+// of its Kraus branches with probability ||Kψ||², so memory stays at 2^n while the
+// average over trajectories converges to the density matrix diagonal. measurements
+// roll independently per trajectory, so the averaged probabilities cover every
+// measurement branch
+
+// runs Trajectories state vector runs up to barrier n and averages their probabilities
+func (c *Circuit) executeTrajectories(atBarrier int) (Result, error) {
+	numQubits := c.NumQubits()
+	if numQubits > maxWires+1 {
+		return Result{}, fmt.Errorf("%w, max: %d", ErrTooManyWires, maxWires)
+	}
+
+	trajectories := c.Trajectories
+	if trajectories == 0 {
+		trajectories = defaultTrajectories
+	}
+	if trajectories < 2 {
+		return Result{}, ErrInvalidTrajectories
+	}
+
+	// running sums of p and p² per basis state for the mean and its standard error
+	sum := make([]float64, 1<<numQubits)
+	sumSquares := make([]float64, 1<<numQubits)
+
+	rng := rand.New(rand.NewSource(c.Seed))
+	run := *c
+	initial := c.Initial.vector(numQubits)
+	stateVector := make([]complex128, 1<<numQubits)
+	for t := 0; t < trajectories; t++ {
+		run.Seed = rng.Int63()
+		copy(stateVector, initial)
+
+		classical := make([]int, c.NumCbits())
+		if err := run.runGates(stateVector, numQubits, classical, 0, atBarrier, true); err != nil {
+			return Result{}, err
+		}
+		for i, amplitude := range stateVector {
+			p := real(amplitude)*real(amplitude) + imag(amplitude)*imag(amplitude)
+			sum[i] += p
+			sumSquares[i] += p * p
+		}
+	}
+
+	n := float64(trajectories)
+	probabilities := make(map[string]float64)
+	intervals := make(map[string]float64)
+	for i := range sum {
+		if sum[i] == 0 {
+			continue
+		}
+		key := strings.Join(intToBitString(i, numQubits), "")
+		mean := sum[i] / n
+		variance := math.Max(0, (sumSquares[i]-n*mean*mean)/(n-1))
+		probabilities[key] = mean
+		intervals[key] = 1.96 * math.Sqrt(variance/n)
+	}
+
+	return Result{
+		Probabilities:       probabilities,
+		ConfidenceIntervals: intervals,
+	}, nil
+}
+
+// picks one Kraus operator with probability ||Kψ||² and applies it, renormalised. the
+// weights are Tr(K ρ K†) over the reduced state of the wires, so only the chosen
+// operator touches the state
+func applyKrausBranch(state []complex128, numQubits int, kraus []Matrix, wires []int, rng *rand.Rand) {
+	rho := wireDensity(state, numQubits, wires)
+	weights := make([]float64, len(kraus))
+	total := 0.0
+	for k, op := range kraus {
+		for _, row := range op.Data {
+			for j, a := range row {
+				for j2, b := range row {
+					weights[k] += real(a * rho[j][j2] * cmplx.Conj(b))
+				}
+			}
+		}
+		total += weights[k]
+	}
+
+	// rounding left over past the last branch goes to the last one with any weight
+	x := rng.Float64() * total
+	chosen := -1
+	for k, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		chosen = k
+		if x < w {
+			break
+		}
+		x -= w
+	}
+	if chosen < 0 {
+		return
+	}
+
+	applyGateKernel(state, numQubits, kraus[chosen], wires)
+	p := 0.0
+	for _, amplitude := range state {
+		p += real(amplitude)*real(amplitude) + imag(amplitude)*imag(amplitude)
+	}
+	scale := complex(1/math.Sqrt(p), 0)
+	for i := range state {
+		state[i] *= scale
+	}
+}
+
+// reduced density matrix of the wires, the first as the top bit of the local index
+func wireDensity(state []complex128, numQubits int, wires []int) [][]complex128 {
+	k := len(wires)
+	dim := 1 << k
+	offsets := make([]int, dim)
+	for j := range offsets {
+		for t, wire := range wires {
+			if j&(1<<(k-1-t)) != 0 {
+				offsets[j] |= wireMask(wire, numQubits)
+			}
+		}
+	}
+	rho := make([][]complex128, dim)
+	for j := range rho {
+		rho[j] = make([]complex128, dim)
+	}
+	masks := sortedMasks(wires, numQubits)
+	for n := 0; n < len(state)>>k; n++ {
+		i := insertZeroBits(n, masks)
+		for j, offset := range offsets {
+			a := state[i|offset]
+			if a == 0 {
+				continue
+			}
+			for j2, offset2 := range offsets {
+				rho[j][j2] += a * cmplx.Conj(state[i|offset2])
+			}
+		}
+	}
+	return rho
+}
@@ -0,0 +1,150 @@
+package quantum
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestTrajectoriesMatchDensity(t *testing.T) {
+	model, err := ParseNoiseModel([]byte(`{
+		"gate_errors": {"default": 0.02, "cnot": 0.1},
+		"gate_times": {"default": 20},
+		"qubits": {"0": {"t1": 300, "t2": 400}, "1": {"t1": 200, "t2": 100}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gates := strings.Split("h0 cnot0,1 ampdamp1(0.2) rx0(pi/3) bitflip0(0.1)", " ")
+	density, _ := NewCircuit(gates)
+	density.Backend = BackendDensity
+	density.Noise = model
+	want, err := density.ExecuteToBarrier(len(gates))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trajectory, _ := NewCircuit(gates)
+	trajectory.Backend = BackendTrajectory
+	trajectory.Noise = model
+	trajectory.Seed = 3
+	trajectory.Trajectories = 4000
+	got, err := trajectory.ExecuteToBarrier(len(gates))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"00", "01", "10", "11"} {
+		// a few interval widths of slack keeps the test from flaking on the seed
+		if diff := math.Abs(got.Probabilities[key] - want.Probabilities[key]); diff > 3*got.ConfidenceIntervals[key]+1e-9 {
+			t.Errorf("P(%s) = %v ± %v, density gives %v", key, got.Probabilities[key], got.ConfidenceIntervals[key], want.Probabilities[key])
+		}
+	}
+}
+
+func TestTrajectoriesAverageMeasurements(t *testing.T) {
+	circuit, _ := NewCircuit(strings.Split("h0 m0->0 x1?c0==1", " "))
+	circuit.Backend = BackendTrajectory
+	circuit.Seed = 11
+	result, err := circuit.ExecuteToBarrier(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Probabilities) != 2 || math.Abs(result.Probabilities["11"]-0.5) > 3*result.ConfidenceIntervals["11"] {
+		t.Errorf("expected both measurement branches near 1/2, got %v", result.Probabilities)
+	}
+	if result.Probabilities["01"] != 0 || result.Probabilities["10"] != 0 {
+		t.Errorf("conditioned x1 should follow the measurement, got %v", result.Probabilities)
+	}
+}
+
+func TestTrajectoriesReproducible(t *testing.T) {
+	run := func(seed int64) Result {
+		circuit, _ := NewCircuit(strings.Split("h0 depol0,1(0.3) cnot0,1", " "))
+		circuit.Backend = BackendTrajectory
+		circuit.Seed = seed
+		circuit.Trajectories = 200
+		result, err := circuit.ExecuteToBarrier(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	a, b, c := run(5), run(5), run(6)
+	for key, p := range a.Probabilities {
+		if b.Probabilities[key] != p {
+			t.Errorf("same seed gave P(%s) = %v and %v", key, p, b.Probabilities[key])
+		}
+	}
+	same := true
+	for key, p := range a.Probabilities {
+		if c.Probabilities[key] != p {
+			same = false
+		}
+	}
+	if same {
+		t.Error("different seeds gave identical averages")
+	}
+}
+
+func TestTrajectoriesNoiselessHasNoSpread(t *testing.T) {
+	circuit, _ := NewCircuit(strings.Split("h0 cnot0,1", " "))
+	circuit.Backend = BackendTrajectory
+	circuit.Trajectories = 10
+	result, err := circuit.ExecuteToBarrier(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"00", "11"} {
+		if math.Abs(result.Probabilities[key]-0.5) > 1e-9 || result.ConfidenceIntervals[key] > 1e-9 {
+			t.Errorf("P(%s) = %v ± %v, want exactly 1/2", key, result.Probabilities[key], result.ConfidenceIntervals[key])
+		}
+	}
+
+	circuit.Trajectories = 1
+	if _, err := circuit.ExecuteToBarrier(2); !errors.Is(err, ErrInvalidTrajectories) {
+		t.Errorf("expected ErrInvalidTrajectories, got %v", err)
+	}
+}
+
+func TestKrausBranchIsNormalised(t *testing.T) {
+	damp, _ := AmplitudeDamping(0.3)
+	depol, _ := Depolarizing(0.2, 2)
+	s := complex(1/math.Sqrt2, 0)
+	tests := []struct {
+		state []complex128
+		kraus []Matrix
+		wires []int
+	}{
+		// the decay branch has no weight on |00⟩
+		{[]complex128{1, 0, 0, 0}, damp.Kraus(), []int{1}},
+		{[]complex128{s, 0, 0, s}, damp.Kraus(), []int{0}},
+		{[]complex128{0, s, 1i * s, 0}, depol.Kraus(), []int{1, 0}},
+	}
+	for _, tt := range tests {
+		for seed := int64(0); seed < 50; seed++ {
+			state := append([]complex128(nil), tt.state...)
+			applyKrausBranch(state, 2, tt.kraus, tt.wires, rand.New(rand.NewSource(seed)))
+			norm := 0.0
+			for _, amplitude := range state {
+				norm += real(amplitude)*real(amplitude) + imag(amplitude)*imag(amplitude)
+			}
+			if math.Abs(norm-1) > 1e-12 {
+				t.Fatalf("%v on wires %v: norm %v after a branch", tt.state, tt.wires, norm)
+			}
+		}
+	}
+
+	// on |00⟩ damping can only leave the state alone
+	state := []complex128{1, 0, 0, 0}
+	for seed := int64(0); seed < 50; seed++ {
+		applyKrausBranch(state, 2, damp.Kraus(), []int{0}, rand.New(rand.NewSource(seed)))
+	}
+	if state[0] != 1 {
+		t.Errorf("damping moved |00⟩ to %v", state)
+	}
+}
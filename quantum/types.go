@@ -1,5 +1,7 @@
 package quantum
 
+import "math/rand"
+
 type Matrix struct {
 	Rows int
 	Cols int
@@ -22,14 +24,78 @@ type GateInterface interface {
 type CircuitGate struct {
 	Gate  GateInterface
 	Wires []int
+	// classical bits written by a measurement
+	Cbits []int
+	// when set the gate only fires if the classical register matches
+	Condition *Condition
+}
+
+// classical condition on a gate; Bit is -1 when the whole register is compared to Value
+type Condition struct {
+	Bit   int
+	Value int
 }
 
 type Circuit struct {
 	Gates []CircuitGate
+	// when > 0 the probability table also shows counts sampled with Seed
+	Shots int
+	Seed  int64
+	// forced measurement outcomes by gate index, the rest are rolled from Seed
+	Outcomes map[int]int
+	// per gate salts bumped by Reroll
+	rolls map[int]int64
+	// simulator used by ExecuteToBarrier, empty means BackendStateVector
+	Backend string
+	// device noise injected after every gate and at measurement, nil for an ideal device
+	Noise *NoiseModel
+	// trajectories averaged by the trajectory backend, 0 means the default
+	Trajectories int
+	// widest bond the mps backend keeps, 0 means the default
+	BondDimension int
+	// bitstrings the mps backend reports amplitudes for, empty lets it choose
+	Bitstrings []string
+	// state the wires start in, nil means |0...0⟩
+	Initial *InitialState
+	// Draw adds each wire's entropy and purity under the table
+	ShowEntanglement bool
+	// Draw adds each wire's Bloch vector beside it
+	ShowBloch bool
+	// states kept to resume from, see SetSnapshotBudget
+	snapshots *snapshotCache
 }
 
 type Result struct {
+	// every amplitude, probability and its symbolic form by bitstring. the state vector
+	// backend leaves them nil past 12 wires and only fills Amplitudes
 	StateVector         map[string]complex128
 	StateVectorSymbolic map[string]string
 	Probabilities       map[string]float64
+	// amplitude of every basis state, wire 0 the top bit of the index, only set by the
+	// state vector backend
+	Amplitudes []complex128
+	// bitstring -> number of shots, only set when the result was sampled
+	Counts map[string]int
+	// classical bits written by measurements, indexed by cbit
+	ClassicalRegister []int
+	// full ρ and Tr(ρ²), only set by the density backend
+	DensityMatrix *Matrix
+	Purity        float64
+	// 95% confidence half-width of each probability, only set by the trajectory backend
+	ConfidenceIntervals map[string]float64
+	// signed Pauli generators of the state, only set by the stabilizer backend
+	Stabilizers []string
+	// truncation error summed over every bond cut and the widest bond reached,
+	// only set by the mps backend
+	TruncationError  float64
+	MaxBondDimension int
+	// draws measurement outcomes for backends whose Probabilities only cover some
+	// bitstrings, nil means sample from Probabilities
+	sampler sampler
+	// backend state answering Pauli expectations, for results that can't list it
+	expecter pauliExpecter
+}
+
+type sampler interface {
+	sample(shots int, rng *rand.Rand) map[string]int
 }
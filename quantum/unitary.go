@@ -0,0 +1,54 @@
+package quantum
+
+import "fmt"
+
+// the full operator of a circuit, built one column at a time by running the
+// gates on each basis state
+
+// 2^n x 2^n operator of the whole circuit
+func (c *Circuit) Unitary() (Matrix, error) {
+	return c.UnitaryToBarrier(len(c.Gates))
+}
+
+// 2^n x 2^n operator of the gates up to barrier n, column j is the circuit applied to |j⟩
+func (c *Circuit) UnitaryToBarrier(atBarrier int) (Matrix, error) {
+	if atBarrier < 1 || atBarrier > len(c.Gates) {
+		return Matrix{}, ErrInvalidBarrier
+	}
+	numQubits := c.NumQubits()
+	if numQubits > maxUnitaryWires+1 {
+		return Matrix{}, fmt.Errorf("%w, max: %d", ErrTooManyWires, maxUnitaryWires)
+	}
+
+	// measurements, resets, channels and classically conditioned gates have no single operator
+	gates := c.Gates[:atBarrier]
+	for i, gate := range gates {
+		switch gate.Gate.(type) {
+		case MeasureGate, ResetGate, ChannelInterface:
+			return Matrix{}, fmt.Errorf("gate %d: %w: %s", i+1, ErrNotUnitary, gate.Gate.FullName())
+		}
+		if gate.Condition != nil {
+			return Matrix{}, fmt.Errorf("gate %d: %w: classically conditioned", i+1, ErrNotUnitary)
+		}
+		if len(c.Noise.after(gate)) > 0 {
+			return Matrix{}, fmt.Errorf("gate %d: %w: noisy", i+1, ErrNotUnitary)
+		}
+	}
+
+	dim := 1 << numQubits
+	unitary := NewMatrix(dim, dim)
+	column := make([]complex128, dim)
+	for j := 0; j < dim; j++ {
+		for i := range column {
+			column[i] = 0
+		}
+		column[j] = 1
+		if err := applyGates(column, gates, numQubits); err != nil {
+			return Matrix{}, err
+		}
+		for i, amplitude := range column {
+			unitary.Data[i][j] = amplitude
+		}
+	}
+	return unitary, nil
+}
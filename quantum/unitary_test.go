@@ -0,0 +1,98 @@
+package quantum
+
+import (
+	"errors"
+	"math/cmplx"
+	"strings"
+	"testing"
+)
+
+func TestUnitaryOfCNOT(t *testing.T) {
+	c, err := NewCircuit([]string{"cnot0,1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := c.Unitary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// |10⟩ and |11⟩ swap, wire 0 being the top bit
+	want := [][]complex128{{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 0, 1}, {0, 0, 1, 0}}
+	for i := range want {
+		for j := range want[i] {
+			if u.Data[i][j] != want[i][j] {
+				t.Fatalf("U[%d][%d] = %v, want %v", i, j, u.Data[i][j], want[i][j])
+			}
+		}
+	}
+}
+
+func TestUnitaryColumnsMatchExecution(t *testing.T) {
+	gates := []string{"h0", "t1", "crx0,2(pi/3)", "swap1,2", "ry1(0.4)", "toff0,1,2", "s2"}
+	c, err := NewCircuit(gates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, barrier := range []int{3, len(gates)} {
+		u, err := c.UnitaryToBarrier(barrier)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// U†U = I
+		for i := 0; i < u.Cols; i++ {
+			for j := 0; j < u.Cols; j++ {
+				sum := complex(0, 0)
+				for k := 0; k < u.Rows; k++ {
+					sum += cmplx.Conj(u.Data[k][i]) * u.Data[k][j]
+				}
+				want := complex(0, 0)
+				if i == j {
+					want = 1
+				}
+				if cmplx.Abs(sum-want) > 1e-9 {
+					t.Fatalf("barrier %d: (U†U)[%d][%d] = %v", barrier, i, j, sum)
+				}
+			}
+		}
+
+		// column j is the state reached from |j⟩
+		for j := 0; j < u.Cols; j++ {
+			c.Initial, _ = ParseInitialState(strings.Join(intToBitString(j, 3), ""))
+			result, err := c.ExecuteToBarrier(barrier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < u.Rows; i++ {
+				key := strings.Join(intToBitString(i, 3), "")
+				if cmplx.Abs(result.StateVector[key]-u.Data[i][j]) > 1e-9 {
+					t.Fatalf("barrier %d: U[%s][%d] = %v, state vector has %v", barrier, key, j, u.Data[i][j], result.StateVector[key])
+				}
+			}
+		}
+		c.Initial = nil
+	}
+}
+
+func TestUnitaryRejectsNonUnitary(t *testing.T) {
+	for _, gates := range [][]string{{"h0", "m0->0"}, {"reset0"}, {"depol0(0.1)"}, {"x0", "x1?c0==1", "m2->0"}} {
+		c, err := NewCircuit(gates)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Unitary(); !errors.Is(err, ErrNotUnitary) {
+			t.Errorf("%v: expected ErrNotUnitary, got %v", gates, err)
+		}
+	}
+
+	// everything before the measurement still has an operator
+	c, _ := NewCircuit([]string{"h0", "m0->0"})
+	if _, err := c.UnitaryToBarrier(1); err != nil {
+		t.Errorf("unexpected error before the measurement: %v", err)
+	}
+
+	c, _ = NewCircuit([]string{"h10"})
+	if _, err := c.Unitary(); !errors.Is(err, ErrTooManyWires) {
+		t.Errorf("expected ErrTooManyWires, got %v", err)
+	}
+}